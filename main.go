@@ -1,33 +1,37 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"openshift-ci-flake-dashboard/pkg"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 )
 
 // method #1 using json struct to store variables
 // filename: userconfig.json
-func readUserConfig(config *pkg.Config) {
+func readUserConfig(config *pkg.Config) error {
 	// Open our jsonFile
 	jsonFile, err := os.Open("userconfig.json")
 	// if we os.Open returns an error then handle it
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
 
 	// defer the closing of our jsonFile so that we can parse it later on
 	defer jsonFile.Close()
 
-	byteValue, _ := io.ReadAll(jsonFile)
-	err = json.Unmarshal(byteValue, &config)
+	byteValue, err := io.ReadAll(jsonFile)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
+	return json.Unmarshal(byteValue, &config)
 }
 
 // # method #2 to use env var files, values seperated by `,`
@@ -46,17 +50,53 @@ func readUserConfigFromEnvFile() map[string]interface{} {
 }
 
 func main() {
+	categories := flag.String("category", "", "comma separated pattern categories to report, all if empty")
+	flag.Parse()
 
 	var userConfig pkg.Config
-	readUserConfig(&userConfig)
+	if err := readUserConfig(&userConfig); err != nil {
+		log.Fatalf("reading userconfig.json: %v", err)
+	}
 	//userConfig := readUserConfigFromEnvFile()
 
-	pkg.Ansi = append(pkg.Ansi, userConfig.Regex)
 	//fmt.Printf("# test config %v \n", userConfig)
 
 	// fmt.Println("Generated with https://github.com/jgwest/odo-tools/ and https://github.com/kadel/odo-tools")
 	// fmt.Println("## FLAKY TESTS: Failed test scenarios in past 14 days")
 	//
-	pkg.PullJobStats(userConfig)
-	pkg.PeriodicJobStats(userConfig)
+	// stop fetching build logs on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var report *pkg.Report
+	var err error
+	if flag.Arg(0) == "analyze-local" {
+		// analyze-local <directory or .tar.gz>: offline, no search.ci
+		if flag.NArg() != 2 {
+			log.Fatalf("usage: %s [-category c1,c2] analyze-local <directory or .tar.gz>", os.Args[0])
+		}
+		report, err = pkg.GenerateLocalReport(ctx, userConfig, flag.Arg(1), pkg.PullJobs, pkg.PeriodicJobs)
+	} else {
+		report, err = pkg.GenerateReport(ctx, userConfig, pkg.PullJobs, pkg.PeriodicJobs)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *categories != "" {
+		report.FilterCategories(strings.Split(*categories, ","))
+	}
+
+	var renderer pkg.Renderer = pkg.MarkdownRenderer{}
+	if err := renderer.Render(os.Stdout, report); err != nil {
+		log.Fatal(err)
+	}
+
+	// 0: complete, 1: failed, 2: partial
+	status := report.Status()
+	if status != pkg.ReportComplete {
+		fmt.Fprintf(os.Stderr, "report is %s: %d data problems\n", status, len(report.AllProblems()))
+	}
+	stop()
+	os.Exit(status.ExitCode())
 }
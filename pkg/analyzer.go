@@ -0,0 +1,478 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Analyzer runs the search, download, de-duplication and scoring steps for
+// one JobKind and returns the scored test failures.
+type Analyzer struct {
+	Config  Config
+	Kind    JobKind
+	Storage BlobStorage
+	Search  SearchClient
+	// HTTPClient is used to download build logs and other artifacts.
+	HTTPClient *http.Client
+	// Concurrency bounds the number of build logs fetched in parallel.
+	Concurrency int
+
+	// classifier labels the fetched runs, set by Analyze.
+	classifier *RunClassifier
+}
+
+// NewAnalyzer ...
+func NewAnalyzer(config Config, kind JobKind, storage *BlobStorage, search SearchClient) *Analyzer {
+	return &Analyzer{
+		Config:      config.WithDefaults(),
+		Kind:        kind,
+		Storage:     *storage,
+		Search:      search,
+		HTTPClient:  http.DefaultClient,
+		Concurrency: config.Concurrency,
+	}
+}
+
+// storageClient reads artifacts from the target's storage through HTTPClient.
+func (a *Analyzer) storageClient() *StorageClient {
+	return NewStorageClient(a.Config.StorageURL, a.HTTPClient)
+}
+
+func (a *Analyzer) searchQuery(filter *JobFilter, patterns []searchPattern) SearchQuery {
+	searches := []string{}
+	for _, p := range patterns {
+		if !containsString(searches, p.Search) {
+			searches = append(searches, p.Search)
+		}
+	}
+	return SearchQuery{
+		Search:     searches,
+		Type:       a.Config.SearchType,
+		Context:    a.Config.Context,
+		MaxAge:     time.Duration(a.Config.MaxAge),
+		MaxMatches: a.Config.MaxMatches,
+		MaxBytes:   a.Config.MaxBytes,
+		Name:       filter.SearchName(a.Kind.JobPrefix(a.Config)),
+	}
+}
+
+// searchPattern is a Pattern with its search and regex compiled and its
+// extractor resolved.
+type searchPattern struct {
+	Pattern
+	search    *regexp.Regexp
+	regex     *regexp.Regexp
+	extractor FailureExtractor
+}
+
+// matchedLines returns the lines of a match context that match the search,
+// the others only surround the failure. Without any, e.g. when search.ci
+// matched a line the regexp package reads differently, the middle line is
+// the matched one.
+func (p searchPattern) matchedLines(match Match) []string {
+	lines := []string{}
+	for _, line := range match.Context {
+		if p.search.MatchString(ansiEscape.ReplaceAllString(line, "")) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 && len(match.Context) > 0 {
+		lines = append(lines, match.Context[len(match.Context)/2])
+	}
+	return lines
+}
+
+// extract returns the test name of a failure line, already cleaned up by
+// cleanLine.
+func (p searchPattern) extract(line string) string {
+	if p.regex == nil {
+		return line
+	}
+	if p.regex.NumSubexp() > 0 {
+		if m := p.regex.FindStringSubmatch(line); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+		return line
+	}
+	return StripAnsi(line, p.regex)
+}
+
+// testKey identifies a test by its normalized name: the same line found by
+// two patterns counts as two tests.
+type testKey struct {
+	pattern string
+	name    string
+}
+
+// Analyze searches for failures of the analyzer's job kind and returns them
+// as a report section, sorted by descending score. Runs whose build log could
+// not be fetched or parsed are recorded in Section.Problems; an error is only
+// returned when the search itself fails.
+func (a *Analyzer) Analyze(ctx context.Context) (*Section, error) {
+	normalizer, err := NewNormalizer(a.Config.Rewrites, a.Config.Scrubbers)
+	if err != nil {
+		return nil, err
+	}
+	a.classifier, err = NewRunClassifier(a.Config.InfraRules)
+	if err != nil {
+		return nil, err
+	}
+
+	// each pattern's regex is stripped from lines on top of lineCleanup
+	patterns := []searchPattern{}
+	for _, p := range a.Config.SearchPatterns() {
+		pattern := searchPattern{Pattern: p}
+		pattern.search, err = regexp.Compile(p.Search)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search: %w", err)
+		}
+		if p.Regex != "" {
+			var err error
+			pattern.regex, err = regexp.Compile(p.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex: %w", err)
+			}
+		}
+		if p.Extractor != "" {
+			var err error
+			pattern.extractor, err = ExtractorByName(p.Extractor)
+			if err != nil {
+				return nil, err
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	filter, err := NewJobFilter(a.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.Search.Search(ctx, a.searchQuery(filter, patterns))
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	// sort the results so the report does not depend on map iteration order
+	runURLs := []string{}
+	for k := range result {
+		runURLs = append(runURLs, k)
+	}
+	sort.Strings(runURLs)
+
+	prefix := a.Kind.JobPrefix(a.Config)
+	runs := []Run{}
+	for _, k := range runURLs {
+		jobName := jobNameFromURL(k)
+		if !filter.Match(prefix, jobName) {
+			continue
+		}
+		runs = append(runs, Run{URL: k, JobName: jobName, Branch: filter.Branch(prefix, jobName)})
+	}
+
+	fetched := a.fetchRuns(ctx, runs)
+
+	section := &Section{Kind: a.Kind.Name(), Title: a.Kind.Title(), Window: time.Duration(a.Config.MaxAge)}
+	for _, p := range patterns {
+		if !containsString(section.Categories, p.CategoryName()) {
+			section.Categories = append(section.Categories, p.CategoryName())
+		}
+	}
+	testFailMap := map[testKey]TestFailEntry{}
+	occurrences := []occurrence{}
+
+	fetchedRuns := []Run{}
+	for i := range fetched {
+		run := &fetched[i].run
+		run.Dimensions = jobDimensions(prefix, run.Branch, run.JobName, run.Labels)
+		fetchedRuns = append(fetchedRuns, *run)
+	}
+	section.Steps = kuttlStepStats(fetchedRuns)
+	section.Infra = infraSummaries(fetchedRuns)
+
+	// runs known besides the failures of each test, to find retests
+	knownRuns := append([]Run{}, fetchedRuns...)
+	if a.Config.History {
+		histories, problems := a.jobHistories(ctx, fetchedRuns)
+		section.Jobs = histories
+		section.Problems = append(section.Problems, problems...)
+		for _, history := range histories {
+			knownRuns = append(knownRuns, history.Records...)
+		}
+		section.JUnit = junitStats(junitRuns(fetchedRuns, histories))
+		section.JUnitAllRuns = true
+	} else {
+		section.JUnit = junitStats(fetchedRuns)
+		if a.Config.Retests {
+			retests, problems := a.prRetests(ctx, fetchedRuns)
+			section.Problems = append(section.Problems, problems...)
+			knownRuns = append(knownRuns, retests...)
+		}
+	}
+
+	// iterate over all results
+	for i, run := range runs {
+		search := result[run.URL]
+		// the search result is still counted when the build log could not be
+		// fetched, it only lacks the run time
+		section.Problems = append(section.Problems, fetched[i].problems...)
+		run = fetched[i].run
+		runTime := run.Time
+		if run.Class == RunInfra && a.Config.InfraFailures == InfraExclude {
+			continue
+		}
+
+		group := a.Kind.Group(a.Config, run)
+		excerpts := &runExcerpts{analyzer: a, ctx: ctx, run: run}
+
+		// line is the failure line in match, to cut an excerpt around it
+		record := func(key testKey, failure Failure, match Match, line string) {
+			entry, exists := testFailMap[key]
+			if !exists {
+				entry = TestFailEntry{Name: failure.Test, Runs: map[string][]Run{}}
+			}
+
+			entry.TestFail++
+
+			if entry.Message == "" {
+				entry.Message, entry.Location = failure.Message, failure.Location
+			}
+
+			if runTime != nil {
+				if entry.LastSeen == nil || runTime.After(*entry.LastSeen) {
+					entry.LastSeen = runTime
+				}
+			}
+
+			// the excerpt of the most recent run is kept
+			if a.Config.Excerpt > 0 && (entry.Excerpt == nil || runAfter(run, Run{Time: entry.Excerpt.Time})) {
+				entry.Excerpt = excerpts.excerpt(line, match)
+			}
+
+			if !containsString(entry.Branches, run.Branch) {
+				entry.Branches = append(entry.Branches, run.Branch)
+			}
+
+			if group != "" {
+				matchFound := false
+				for _, existingEntry := range entry.Groups {
+					if existingEntry == group {
+						matchFound = true
+					}
+				}
+
+				if !matchFound {
+					entry.Groups = append(entry.Groups, group)
+				}
+
+				// Add the run for the group
+				entry.Runs[group] = append(entry.Runs[group], run)
+			}
+
+			testFailMap[key] = entry
+
+			// only real failure messages are clustered: similar test names
+			// are no sign of a shared root cause
+			if failure.Message != "" {
+				occurrences = append(occurrences, occurrence{key: key, test: entry.Name, message: normalizer.Key(failure.Message), run: run})
+			}
+		}
+
+		for searchStr, matches := range search {
+			for _, pattern := range patterns {
+				if pattern.Search != searchStr {
+					continue
+				}
+
+				// extracted failures are counted once per run, the context
+				// of neighbouring matches overlaps
+				if pattern.extractor != nil {
+					seen := map[string]bool{}
+					for _, match := range matches {
+						for _, failure := range pattern.extractor.Extract(match.Context) {
+							key := testKey{pattern: pattern.Name, name: normalizer.Key(failure.Test)}
+							if seen[key.name] {
+								continue
+							}
+							seen[key.name] = true
+							record(key, failure, match, failureLine(match, failure.Test))
+						}
+					}
+					continue
+				}
+
+				// the other context lines are only excerpt material
+				for _, match := range matches {
+					lines := []string{}
+					for _, line := range pattern.matchedLines(match) {
+						name := pattern.extract(cleanLine(line))
+						key := testKey{pattern: pattern.Name, name: normalizer.Key(name)}
+
+						// de-duplication
+						// count each line only once
+						dup := false
+						for _, l := range lines {
+							if l == key.name {
+								dup = true
+							}
+						}
+						if dup {
+							continue
+						}
+						lines = append(lines, key.name)
+
+						record(key, Failure{Test: name}, match, line)
+					}
+				}
+			}
+		}
+	}
+
+	categories := map[string]string{}
+	for _, p := range patterns {
+		categories[p.Name] = p.CategoryName()
+	}
+
+	reported := map[testKey]bool{}
+	for key, entry := range testFailMap {
+		test := entry.Name
+		if len(entry.Groups) < a.Kind.MinGroups() {
+			continue
+		}
+		reported[key] = true
+
+		a.Kind.SortGroups(entry.Groups)
+		sort.Strings(entry.Branches)
+
+		testReport := TestReport{
+			Name:     test,
+			Pattern:  key.pattern,
+			Category: categories[key.pattern],
+			Message:  entry.Message,
+			Location: entry.Location,
+			Excerpt:  entry.Excerpt,
+			Fails:    entry.TestFail,
+			LastSeen: entry.LastSeen,
+			Branches: entry.Branches,
+			JUnit:    matchJUnit(section.JUnit, test),
+		}
+		failures := []Run{}
+		for _, group := range entry.Groups {
+			testReport.Groups = append(testReport.Groups, RunGroup{
+				Key:   group,
+				Label: a.Kind.GroupLabel(group),
+				URL:   a.Kind.GroupURL(a.Config, group),
+				Runs:  entry.Runs[group],
+			})
+			failures = append(failures, entry.Runs[group]...)
+		}
+		testReport.Retest = retestVerdict(failures, knownRuns)
+		testReport.Breakdown = breakdown(failures)
+		testReport.Score = scoreEntry(entry, testReport.Retest)
+
+		testReport.FailedRuns, testReport.TotalRuns = testRunRate(testReport.Groups, section.Jobs)
+
+		section.Tests = append(section.Tests, testReport)
+	}
+
+	sortTests(section.Tests)
+	section.Signatures = failureSignatures(occurrences, reported, categories)
+
+	if a.Config.Bugs {
+		trackers, problems := a.trackers(ctx, section.Tests)
+		section.Problems = append(section.Problems, problems...)
+		for i := range section.Tests {
+			section.Tests[i].TrackedBy = trackers[section.Tests[i].Name]
+		}
+	}
+
+	return section, nil
+}
+
+// scoreEntry returns the failure score of an entry. Confirmed flakes weigh
+// more than failures that may be caused by the PR.
+func scoreEntry(entry TestFailEntry, retest RetestVerdict) int {
+	daysSinceLastSeen := 1
+
+	lastSeenTime := entry.LastSeen
+	if lastSeenTime != nil {
+		daysSinceLastSeen = int(time.Since(*lastSeenTime).Hours() / 24)
+	}
+
+	if daysSinceLastSeen == 0 {
+		daysSinceLastSeen = 1
+	}
+
+	groupsSize := len(entry.Groups)
+
+	if groupsSize > 6 {
+		// >6 PRs does not imply any further strength than 6 PRs, for score calculation purposes.
+		groupsSize = 6
+	}
+
+	score := (10 * groupsSize * entry.TestFail) / (daysSinceLastSeen)
+
+	// Minimum score if there is at least one PR, and at least one fail, is 1
+	if score == 0 && len(entry.Groups) > 0 && entry.TestFail > 0 {
+		score = 1
+	}
+
+	if retest == RetestConfirmedFlake {
+		score *= confirmedFlakeWeight
+	}
+
+	return score
+}
+
+func sortTests(tests []TestReport) {
+	sort.Slice(tests, func(i, j int) bool {
+		return lessTest(tests[i], tests[j])
+	})
+}
+
+// lessTest orders tests by descending score, fails and group count, then
+// ascending by name, pattern and category, so the order does not depend on
+// map iteration.
+func lessTest(a, b TestReport) bool {
+	one := a.Score
+	two := b.Score
+
+	// Primary sort: descending by score
+	if one != two {
+		return one > two
+	}
+
+	// Secondary sort: descending by fails
+	one = a.Fails
+	two = b.Fails
+	if one != two {
+		return one > two
+	}
+
+	// Tertiary sort: descending by group list size
+	one = len(a.Groups)
+	two = len(b.Groups)
+	if one != two {
+		return one > two
+	}
+
+	// Finally, sort ascending by name, then by pattern and category: the
+	// same test can be found by several patterns
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	if a.Pattern != b.Pattern {
+		return a.Pattern < b.Pattern
+	}
+	return a.Category < b.Category
+}
+
+func (a *Analyzer) problem(stage, runURL string, err error) DataProblem {
+	target := Target{RepoOrg: a.Config.RepoOrg, RepoName: a.Config.RepoName}
+	return DataProblem{Target: target.String(), Kind: a.Kind.Name(), Stage: stage, RunURL: runURL, Err: err.Error()}
+}
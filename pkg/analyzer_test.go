@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSearch returns a fixed result and records the last query.
+type fakeSearch struct {
+	result Result
+	query  SearchQuery
+}
+
+func (s *fakeSearch) Search(ctx context.Context, query SearchQuery) (Result, error) {
+	s.query = query
+	return s.result, nil
+}
+
+func TestAnalyzeContext(t *testing.T) {
+	const (
+		search = "(?i)--- FAIL: kuttl/harness/1-"
+		job    = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	)
+	buildLog := strings.Join([]string{
+		"    logger.go:42: 10:45:03 | 1-001_foo/2-check | test step failed 2-check",
+		"some line before",
+		"--- FAIL: kuttl/harness/1-001_foo (12.30s)",
+		"--- FAIL: kuttl (123.00s)",
+		"FAIL",
+	}, "\n")
+
+	objects := map[string]string{}
+	result := Result{}
+	for i, pr := range []int{101, 102} {
+		run := ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: pr, Job: job, BuildID: fmt.Sprint(1700000000000000001 + i)}
+		addHistoryRun(objects, run, time.Hour, "FAILURE")
+		objects[run.Bucket+"/"+run.Path()+"/build-log.txt"] = buildLog
+		result[run.ViewURL()] = map[string][]Match{search: {{
+			FileType: "build-log",
+			Context:  []string{"some line before", "--- FAIL: kuttl/harness/1-001_foo (12.30s)", "--- FAIL: kuttl (123.00s)"},
+		}}}
+	}
+	_, server := newFakeGCS(t, objects)
+	storage, err := NewBlobStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{
+		RepoOrg:    "redhat-developer",
+		RepoName:   "gitops-operator",
+		SearchStr:  search,
+		Regex:      `---\s+FAIL:\s+kuttl/harness/`,
+		Context:    1,
+		Excerpt:    1,
+		StorageURL: server.URL,
+	}
+	searchClient := &fakeSearch{result: result}
+	analyzer := NewAnalyzer(config, PullJobs, storage, searchClient)
+	analyzer.HTTPClient = server.Client()
+
+	section, err := analyzer.Analyze(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searchClient.query.Context != 1 {
+		t.Errorf("search context = %d, want 1", searchClient.query.Context)
+	}
+	if len(section.Problems) != 0 {
+		t.Errorf("Analyze() problems = %+v", section.Problems)
+	}
+
+	// the lines around the match are no tests
+	names := []string{}
+	for _, test := range section.Tests {
+		names = append(names, test.Name)
+	}
+	if len(section.Tests) != 1 || section.Tests[0].Name != "1-001_foo" || section.Tests[0].Fails != 2 {
+		t.Fatalf("Analyze() tests = %q, want only 1-001_foo failing twice", names)
+	}
+
+	// they are the excerpt
+	excerpt := section.Tests[0].Excerpt
+	want := "some line before\n--- FAIL: kuttl/harness/1-001_foo (12.30s)\n--- FAIL: kuttl (123.00s)"
+	if excerpt == nil || strings.Join(excerpt.Lines, "\n") != want {
+		t.Errorf("Analyze() excerpt = %+v, want the lines %q", excerpt, want)
+	}
+}
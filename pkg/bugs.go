@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bugSearchMaxAge is how far back bugs are searched. A bug tracking a flake
+// is usually older than the report window.
+const bugSearchMaxAge = 90 * 24 * time.Hour
+
+// bugSearchMaxLength bounds the length of the regex of one bug search, so the
+// request URL stays well below the limits of search.ci and its proxies.
+const bugSearchMaxLength = 2000
+
+// bugSearchTypes are the search.ci indexes searched for test names.
+var bugSearchTypes = []SearchType{SearchTypeBug, SearchTypeIssue}
+
+// BugLink is a bug or issue whose text mentions a failing test.
+type BugLink struct {
+	URL string
+	// ID is the short bug name, e.g. "Bug 2012345" or "OCPBUGS-1234".
+	ID     string
+	Title  string
+	Status string
+}
+
+// trackers searches the bug trackers for the names of tests, in as few queries
+// per tracker as bugSearchMaxLength allows, and returns the bugs mentioning
+// each test by test name.
+func (a *Analyzer) trackers(ctx context.Context, tests []TestReport) (map[string][]BugLink, []DataProblem) {
+	names := []string{}
+	patterns := []string{}
+	for _, test := range tests {
+		name := strings.TrimSpace(test.Name)
+		if name == "" || containsString(names, name) {
+			continue
+		}
+		names = append(names, name)
+		patterns = append(patterns, regexp.QuoteMeta(name))
+	}
+
+	links := map[string][]BugLink{}
+	problems := []DataProblem{}
+	if len(names) == 0 {
+		return links, problems
+	}
+
+	for _, searchType := range bugSearchTypes {
+		for _, search := range bugSearches(patterns, bugSearchMaxLength) {
+			result, err := a.Search.Search(ctx, SearchQuery{
+				Search: []string{search},
+				Type:   searchType,
+				MaxAge: bugSearchMaxAge,
+			})
+			if err != nil {
+				problems = append(problems, a.problem(StageBugs, "", err))
+				continue
+			}
+			addBugLinks(links, names, result)
+		}
+	}
+
+	// tests are keyed by their untrimmed name
+	for _, test := range tests {
+		if trimmed := strings.TrimSpace(test.Name); trimmed != test.Name {
+			links[test.Name] = links[trimmed]
+		}
+	}
+
+	return links, problems
+}
+
+// bugSearches joins patterns into alternations of at most maxLength bytes. A
+// pattern longer than maxLength is searched on its own.
+func bugSearches(patterns []string, maxLength int) []string {
+	searches := []string{}
+	current := ""
+	for _, pattern := range patterns {
+		if current != "" && len(current)+len("|")+len(pattern) > maxLength {
+			searches = append(searches, current)
+			current = ""
+		}
+		if current != "" {
+			current += "|"
+		}
+		current += pattern
+	}
+	if current != "" {
+		searches = append(searches, current)
+	}
+	return searches
+}
+
+// addBugLinks adds the bugs of a search result to the links of the names
+// their text mentions.
+func addBugLinks(links map[string][]BugLink, names []string, result Result) {
+	bugURLs := []string{}
+	for bugURL := range result {
+		bugURLs = append(bugURLs, bugURL)
+	}
+	sort.Strings(bugURLs)
+
+	for _, bugURL := range bugURLs {
+		for _, matches := range result[bugURL] {
+			for _, match := range matches {
+				text := strings.Join(match.Context, "\n")
+				info := match.Bug
+				if info == nil {
+					info = match.Issue
+				}
+				link := BugLink{URL: bugURL, ID: bugID(bugURL)}
+				if info != nil {
+					link.Title, link.Status = info.Name, info.Status
+					text += "\n" + info.Name
+				}
+
+				for _, name := range names {
+					if strings.Contains(text, name) && !hasBugLink(links[name], bugURL) {
+						links[name] = append(links[name], link)
+					}
+				}
+			}
+		}
+	}
+}
+
+func hasBugLink(links []BugLink, bugURL string) bool {
+	for _, link := range links {
+		if link.URL == bugURL {
+			return true
+		}
+	}
+	return false
+}
+
+// bugID returns the short name of a Bugzilla (show_bug.cgi?id=N) or Jira
+// (/browse/KEY-N) URL, or the URL itself.
+func bugID(bugURL string) string {
+	u, err := url.Parse(bugURL)
+	if err != nil {
+		return bugURL
+	}
+	if id := u.Query().Get("id"); id != "" {
+		return "Bug " + id
+	}
+	if strings.Contains(u.Path, "/browse/") {
+		return path.Base(u.Path)
+	}
+	return bugURL
+}
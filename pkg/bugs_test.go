@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// bugSearch answers bug searches with a bug for every test name searched,
+// and records the queries.
+type bugSearch struct {
+	queries []SearchQuery
+}
+
+func (s *bugSearch) Search(ctx context.Context, query SearchQuery) (Result, error) {
+	s.queries = append(s.queries, query)
+	result := Result{}
+	for _, name := range strings.Split(query.Search[0], "|") {
+		name = strings.ReplaceAll(name, `\`, "")
+		bugURL := fmt.Sprintf("https://issues.redhat.com/browse/%s-%s", strings.ToUpper(string(query.Type)), name)
+		result[bugURL] = map[string][]Match{query.Search[0]: {{Context: []string{"fails in " + name}}}}
+	}
+	return result, nil
+}
+
+func TestBugSearches(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"a", "b", "c"}, []string{"a|b|c"}},
+		{[]string{"aaaa", "bbbb", "c"}, []string{"aaaa", "bbbb|c"}},
+		// a pattern too long for any search is searched on its own
+		{[]string{"a", "bbbbbbbbbbbb", "c"}, []string{"a", "bbbbbbbbbbbb", "c"}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		got := bugSearches(tt.patterns, 6)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bugSearches(%q, 6) = %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestTrackers(t *testing.T) {
+	tests := []TestReport{}
+	for i := 0; i < 200; i++ {
+		tests = append(tests, TestReport{Name: fmt.Sprintf("1-%03d_validate_something_long.enough", i)})
+	}
+	// untrimmed names are searched trimmed, once
+	tests = append(tests, TestReport{Name: " 1-000_validate_something_long.enough "})
+
+	search := &bugSearch{}
+	a := &Analyzer{Search: search}
+	links, problems := a.trackers(context.Background(), tests)
+	if len(problems) != 0 {
+		t.Errorf("trackers() problems = %+v", problems)
+	}
+
+	searched := map[SearchType][]string{}
+	for _, query := range search.queries {
+		if len(query.Search[0]) > bugSearchMaxLength {
+			t.Errorf("trackers() searched %d bytes, want at most %d", len(query.Search[0]), bugSearchMaxLength)
+		}
+		if _, err := regexp.Compile(query.Search[0]); err != nil {
+			t.Errorf("trackers() searched an invalid regex: %v", err)
+		}
+		searched[query.Type] = append(searched[query.Type], strings.Split(query.Search[0], "|")...)
+	}
+	if len(search.queries) <= len(bugSearchTypes) {
+		t.Errorf("trackers() sent %d queries, want the names split over several per tracker", len(search.queries))
+	}
+	for _, searchType := range bugSearchTypes {
+		if len(searched[searchType]) != 200 {
+			t.Errorf("trackers() searched %d names in %s, want 200", len(searched[searchType]), searchType)
+		}
+	}
+
+	wantLinks := map[string][]string{
+		tests[0].Name:   {"BUG-1-000_validate_something_long.enough", "ISSUE-1-000_validate_something_long.enough"},
+		tests[199].Name: {"BUG-1-199_validate_something_long.enough", "ISSUE-1-199_validate_something_long.enough"},
+		tests[200].Name: {"BUG-1-000_validate_something_long.enough", "ISSUE-1-000_validate_something_long.enough"},
+	}
+	for name, want := range wantLinks {
+		ids := []string{}
+		for _, link := range links[name] {
+			ids = append(ids, link.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("trackers() links of %q = %q, want %q", name, ids, want)
+		}
+	}
+}
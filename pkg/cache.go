@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheMaxAge is how long cache entries are kept before NewBlobStorage
+// removes them.
+const cacheMaxAge = 3 * 7 * 24 * time.Hour
+
+// NewBlobStorage ...
+func NewBlobStorage(pathParam string) (*BlobStorage, error) {
+	blobStorage := BlobStorage{
+		path: pathParam,
+	}
+
+	if err := os.MkdirAll(pathParam, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(pathParam)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		// Delete cache entries older than 3 weeks, and temp files left
+		// behind by an interrupted write
+		if time.Since(info.ModTime()) > cacheMaxAge || strings.HasPrefix(f.Name(), ".tmp-") {
+			err := os.Remove(filepath.Join(pathParam, f.Name()))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &blobStorage, nil
+}
+
+// cacheKey returns the stable file name used for a URL.
+func cacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s BlobStorage) blobPath(key string) string {
+	return filepath.Join(s.path, cacheKey(key))
+}
+
+func (s BlobStorage) metadataPath(key string) string {
+	return s.blobPath(key) + ".json"
+}
+
+func (s BlobStorage) store(key string, value []byte, statusCode int) error {
+	sum := sha256.Sum256(value)
+	metadata := CacheMetadata{
+		SourceURL:   key,
+		FetchedAt:   time.Now().UTC(),
+		Size:        int64(len(value)),
+		StatusCode:  statusCode,
+		ContentHash: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// blob first, so a present sidecar always points at a complete blob
+	if err := s.writeFile(s.blobPath(key), value); err != nil {
+		return err
+	}
+	return s.writeFile(s.metadataPath(key), metadataBytes)
+}
+
+// writeFile writes data to a temp file in the cache dir and renames it into
+// place, so readers never observe a partially written file.
+func (s BlobStorage) writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(s.path, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// retrieve returns the cached value for key. The boolean reports whether
+// the key was found in the cache.
+func (s BlobStorage) retrieve(key string) (string, bool, error) {
+	if _, err := s.metadata(key); err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	contents, err := os.ReadFile(s.blobPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	return string(contents), true, nil
+}
+
+func (s BlobStorage) metadata(key string) (*CacheMetadata, error) {
+	contents, err := os.ReadFile(s.metadataPath(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata CacheMetadata
+	if err := json.Unmarshal(contents, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,340 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Search defaults, matching what the dashboard has always queried.
+const (
+	DefaultMaxAge     = 14 * 24 * time.Hour
+	DefaultMaxMatches = 5
+	DefaultMaxBytes   = 20971520
+	DefaultContext    = 0
+)
+
+// Accepted ranges for the search options.
+const (
+	minMaxAge     = time.Hour
+	maxMaxAge     = 90 * 24 * time.Hour
+	maxMaxMatches = 100
+	maxMaxBytes   = 1 << 30
+	maxContext    = 50
+	maxExcerpt    = 50
+)
+
+// Duration is a time.Duration that is written in config files either as a Go
+// duration ("72h") or as a number of days ("3d").
+type Duration time.Duration
+
+// ParseDuration parses a Go duration or a "<n>d" number of days.
+func ParseDuration(s string) (Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return Duration(time.Duration(n) * 24 * time.Hour), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return Duration(d), nil
+}
+
+// UnmarshalJSON ...
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"72h\" or \"3d\": %w", err)
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON ...
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON records which options the config file sets, so that a
+// target can turn off a feature or set a 0 the top level overrides.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	// config has the fields but not the methods of Config
+	type config Config
+	if err := json.Unmarshal(b, (*config)(c)); err != nil {
+		return err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	c.set = map[string]bool{}
+	for key := range keys {
+		c.set[key] = true
+	}
+	return nil
+}
+
+// isSet tells whether the config sets the option key, either in the config
+// file or to a non-zero value in code.
+func (c Config) isSet(key string, nonZero bool) bool {
+	return nonZero || c.set[key]
+}
+
+// WithDefaults returns a copy of the config with unset search options filled
+// in from the defaults.
+func (c Config) WithDefaults() Config {
+	if c.MaxAge == 0 {
+		c.MaxAge = Duration(DefaultMaxAge)
+	}
+	if c.MaxMatches == 0 {
+		c.MaxMatches = DefaultMaxMatches
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = DefaultMaxBytes
+	}
+	if c.SearchType == "" {
+		c.SearchType = SearchTypeBuildLog
+	}
+	return c
+}
+
+// AllTargets returns the per-target configs to analyze, with unset options
+// inherited from c and defaults applied.
+func (c Config) AllTargets() []Config {
+	if len(c.Targets) == 0 {
+		target := c
+		target.Targets = nil
+		return []Config{target.WithDefaults()}
+	}
+
+	targets := []Config{}
+	for _, target := range c.Targets {
+		targets = append(targets, target.inherit(c).WithDefaults())
+	}
+	return targets
+}
+
+// SearchPatterns returns the patterns of the target, or a single unnamed
+// pattern made of SearchStr and Regex.
+func (c Config) SearchPatterns() []Pattern {
+	if len(c.Patterns) > 0 {
+		return c.Patterns
+	}
+	return []Pattern{{Search: c.SearchStr, Regex: c.Regex, Extractor: c.Extractor}}
+}
+
+// CategoryName is the category of the pattern, its name by default.
+func (p Pattern) CategoryName() string {
+	if p.Category != "" {
+		return p.Category
+	}
+	return p.Name
+}
+
+// inherit fills the options left unset in a target from parent.
+func (c Config) inherit(parent Config) Config {
+	// a target searching for its own failures does not inherit patterns
+	if c.Patterns == nil && c.SearchStr == "" {
+		c.Patterns = parent.Patterns
+	}
+	if c.RepoOrg == "" {
+		c.RepoOrg = parent.RepoOrg
+	}
+	if c.SearchStr == "" {
+		c.SearchStr = parent.SearchStr
+	}
+	if c.Regex == "" {
+		c.Regex = parent.Regex
+	}
+	if c.Extractor == "" {
+		c.Extractor = parent.Extractor
+	}
+	if c.InfraRules == nil {
+		c.InfraRules = parent.InfraRules
+	}
+	if c.InfraFailures == "" {
+		c.InfraFailures = parent.InfraFailures
+	}
+	if c.Rewrites == nil {
+		c.Rewrites = parent.Rewrites
+	}
+	if c.Scrubbers == nil {
+		c.Scrubbers = parent.Scrubbers
+	}
+	if c.SearchURL == "" {
+		c.SearchURL = parent.SearchURL
+	}
+	if c.StorageURL == "" {
+		c.StorageURL = parent.StorageURL
+	}
+	if c.SearchType == "" {
+		c.SearchType = parent.SearchType
+	}
+	if !c.isSet("bugs", c.Bugs) {
+		c.Bugs = parent.Bugs
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = parent.Concurrency
+	}
+	if !c.isSet("junit", c.JUnit) {
+		c.JUnit = parent.JUnit
+	}
+	if !c.isSet("kuttlSteps", c.KuttlSteps) {
+		c.KuttlSteps = parent.KuttlSteps
+	}
+	if !c.isSet("history", c.History) {
+		c.History = parent.History
+	}
+	if !c.isSet("retests", c.Retests) {
+		c.Retests = parent.Retests
+	}
+	if c.MaxAge == 0 {
+		c.MaxAge = parent.MaxAge
+	}
+	if c.MaxMatches == 0 {
+		c.MaxMatches = parent.MaxMatches
+	}
+	if !c.isSet("excerpt", c.Excerpt != 0) {
+		c.Excerpt = parent.Excerpt
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = parent.MaxBytes
+	}
+	if !c.isSet("context", c.Context != 0) {
+		c.Context = parent.Context
+	}
+	if c.Branches == nil {
+		c.Branches = parent.Branches
+	}
+	if c.IncludeJobs == nil {
+		c.IncludeJobs = parent.IncludeJobs
+	}
+	if c.ExcludeJobs == nil {
+		c.ExcludeJobs = parent.ExcludeJobs
+	}
+	c.Targets = nil
+	return c
+}
+
+// Validate checks every target of the config.
+func (c Config) Validate() error {
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency %d must not be negative", c.Concurrency)
+	}
+
+	seen := map[string]bool{}
+	for _, target := range c.AllTargets() {
+		name := target.RepoOrg + "/" + target.RepoName
+		if err := target.validateTarget(); err != nil {
+			return fmt.Errorf("target %s: %w", name, err)
+		}
+		if seen[name] {
+			return fmt.Errorf("target %s is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// validateTarget checks that a single target is complete and that its search
+// options are in sane ranges.
+func (c Config) validateTarget() error {
+	if c.RepoOrg == "" || c.RepoName == "" {
+		return fmt.Errorf("repoOrg and repoName must be set")
+	}
+	if c.SearchStr == "" && len(c.Patterns) == 0 {
+		return fmt.Errorf("searchStr or patterns must be set")
+	}
+	if _, err := regexp.Compile(c.SearchStr); err != nil {
+		return fmt.Errorf("invalid searchStr: %w", err)
+	}
+	if _, err := regexp.Compile(c.Regex); err != nil {
+		return fmt.Errorf("invalid regex: %w", err)
+	}
+	if c.Extractor != "" {
+		if _, err := ExtractorByName(c.Extractor); err != nil {
+			return err
+		}
+	}
+	if _, err := NewNormalizer(c.Rewrites, c.Scrubbers); err != nil {
+		return err
+	}
+	if _, err := NewRunClassifier(c.InfraRules); err != nil {
+		return err
+	}
+	if c.InfraFailures != "" && c.InfraFailures != InfraCount && c.InfraFailures != InfraExclude {
+		return fmt.Errorf("infraFailures %q must be %q or %q", c.InfraFailures, InfraCount, InfraExclude)
+	}
+	names := map[string]bool{}
+	for _, p := range c.Patterns {
+		if p.Name == "" || p.Search == "" {
+			return fmt.Errorf("patterns must have a name and a search")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("pattern %s is listed more than once", p.Name)
+		}
+		names[p.Name] = true
+		if _, err := regexp.Compile(p.Search); err != nil {
+			return fmt.Errorf("pattern %s: invalid search: %w", p.Name, err)
+		}
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("pattern %s: invalid regex: %w", p.Name, err)
+		}
+		if p.Extractor != "" {
+			if _, err := ExtractorByName(p.Extractor); err != nil {
+				return fmt.Errorf("pattern %s: %w", p.Name, err)
+			}
+		}
+	}
+	if _, err := NewJobFilter(c); err != nil {
+		return err
+	}
+	if c.SearchType != SearchTypeBuildLog && c.SearchType != SearchTypeJUnit {
+		return fmt.Errorf("searchType %q must be %q or %q", c.SearchType, SearchTypeBuildLog, SearchTypeJUnit)
+	}
+	if maxAge := time.Duration(c.MaxAge); maxAge < minMaxAge || maxAge > maxMaxAge {
+		return fmt.Errorf("maxAge %s must be between %s and %s", maxAge, minMaxAge, maxMaxAge)
+	}
+	if c.MaxMatches < 1 || c.MaxMatches > maxMaxMatches {
+		return fmt.Errorf("maxMatches %d must be between 1 and %d", c.MaxMatches, maxMaxMatches)
+	}
+	if c.MaxBytes < 1 || c.MaxBytes > maxMaxBytes {
+		return fmt.Errorf("maxBytes %d must be between 1 and %d", c.MaxBytes, maxMaxBytes)
+	}
+	if c.Context < 0 || c.Context > maxContext {
+		return fmt.Errorf("context %d must be between 0 and %d", c.Context, maxContext)
+	}
+	if c.Excerpt < 0 || c.Excerpt > maxExcerpt {
+		return fmt.Errorf("excerpt %d must be between 0 and %d", c.Excerpt, maxExcerpt)
+	}
+	return nil
+}
+
+// formatWindow renders a search window for report headings, e.g. "14 days".
+func formatWindow(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		days := int(d / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	}
+	return d.String()
+}
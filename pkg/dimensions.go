@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Prow job labels set by ci-operator.
+const (
+	labelVariant = "ci-operator.openshift.io/variant"
+	labelCloud   = "ci-operator.openshift.io/cloud"
+)
+
+// platforms are the job name tokens naming a cloud platform.
+var platforms = []string{"aws", "gcp", "azure", "metal", "vsphere", "openstack", "ibmcloud", "nutanix", "ovirt", "alibaba", "powervs"}
+
+// versionToken matches an OpenShift version job name token, "v4.14" or "4.14".
+var versionToken = regexp.MustCompile(`^v?(\d+\.\d+)$`)
+
+// JobDimensions describe the environment of a run.
+type JobDimensions struct {
+	// Version is the OpenShift version, e.g. "4.14".
+	Version string
+	// Platform is the cloud platform, e.g. "aws".
+	Platform string
+	// Variant is the rest of the job name, e.g. "kuttl-sequential".
+	Variant string
+}
+
+// jobDimensions parses the dimensions of a job named <prefix><branch>-<rest>.
+// The ci-operator variant and cloud labels of the prow job win over the job
+// name.
+func jobDimensions(prefix, branch, jobName string, labels map[string]string) JobDimensions {
+	dims := JobDimensions{}
+
+	rest := strings.TrimPrefix(strings.TrimPrefix(jobName, prefix), branch+"-")
+	variant := []string{}
+	for _, token := range strings.Split(rest, "-") {
+		if m := versionToken.FindStringSubmatch(token); m != nil && dims.Version == "" {
+			dims.Version = m[1]
+			continue
+		}
+		if containsString(platforms, token) && dims.Platform == "" {
+			dims.Platform = token
+			continue
+		}
+		variant = append(variant, token)
+	}
+	dims.Variant = strings.Join(variant, "-")
+
+	// the ci-operator variant usually names the version, e.g. "v4.14" or
+	// "ocp-4.14-aws"
+	for _, token := range strings.Split(labels[labelVariant], "-") {
+		if m := versionToken.FindStringSubmatch(token); m != nil {
+			dims.Version = m[1]
+		}
+	}
+	if cloud := labels[labelCloud]; cloud != "" {
+		dims.Platform = cloud
+	}
+
+	return dims
+}
+
+// Breakdown counts the failing runs of a test per dimension value.
+type Breakdown struct {
+	Versions  []DimensionCount
+	Platforms []DimensionCount
+	Variants  []DimensionCount
+}
+
+// DimensionCount is the number of failing runs with one dimension value.
+type DimensionCount struct {
+	Value string
+	Fails int
+}
+
+// breakdown counts each distinct run once per dimension. Unknown values are
+// left out.
+func breakdown(runs []Run) Breakdown {
+	versions, platforms, variants := map[string]int{}, map[string]int{}, map[string]int{}
+	seen := map[string]bool{}
+	for _, run := range runs {
+		if seen[run.URL] {
+			continue
+		}
+		seen[run.URL] = true
+		if run.Dimensions.Version != "" {
+			versions[run.Dimensions.Version]++
+		}
+		if run.Dimensions.Platform != "" {
+			platforms[run.Dimensions.Platform]++
+		}
+		if run.Dimensions.Variant != "" {
+			variants[run.Dimensions.Variant]++
+		}
+	}
+	return Breakdown{
+		Versions:  dimensionCounts(versions),
+		Platforms: dimensionCounts(platforms),
+		Variants:  dimensionCounts(variants),
+	}
+}
+
+// dimensionCounts sorts counts by descending fails, then by value.
+func dimensionCounts(counts map[string]int) []DimensionCount {
+	all := []DimensionCount{}
+	for value, fails := range counts {
+		all = append(all, DimensionCount{Value: value, Fails: fails})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Fails != all[j].Fails {
+			return all[i].Fails > all[j].Fails
+		}
+		return all[i].Value < all[j].Value
+	})
+	return all
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Excerpt is the part of a build log around a failure.
+type Excerpt struct {
+	// URL is the build log the lines are from.
+	URL   string
+	Time  *time.Time
+	Lines []string
+}
+
+// runExcerpts cuts the excerpts of the failures of one run, reading its
+// build log from the cache at most once.
+type runExcerpts struct {
+	analyzer *Analyzer
+	ctx      context.Context
+	run      Run
+	log      []string
+	loaded   bool
+}
+
+// excerpt returns the Config.Excerpt lines of the build log before and after
+// the first line containing line. Without a build log, or when it does not
+// contain the line, the search match context is used instead.
+func (e *runExcerpts) excerpt(line string, match Match) *Excerpt {
+	excerpt := &Excerpt{URL: e.run.BuildLogURL, Time: e.run.Time}
+	if lines, ok := excerptLines(e.buildLog(), line, e.analyzer.Config.Excerpt); ok {
+		excerpt.Lines = lines
+		return excerpt
+	}
+
+	excerpt.Lines = stripColors(match.Context)
+	if match.MoreLines > 0 {
+		excerpt.Lines = append(excerpt.Lines, fmt.Sprintf("... %d more lines", match.MoreLines))
+	}
+	return excerpt
+}
+
+// buildLog returns the lines of the build log without colors, nil when
+// fetchRun could not download it. The problem was recorded then.
+func (e *runExcerpts) buildLog() []string {
+	if e.loaded {
+		return e.log
+	}
+	e.loaded = true
+	if e.run.Prow == nil {
+		return nil
+	}
+	contents, err := downloadTestLog(e.ctx, e.analyzer.storageClient(), e.run.URL, *e.run.Prow, e.analyzer.Storage)
+	if err != nil {
+		return nil
+	}
+	e.log = stripColors(strings.Split(strings.ReplaceAll(contents, "\r\n", "\n"), "\n"))
+	return e.log
+}
+
+// excerptLines returns up to n lines before and after the first line of log
+// containing line, both stripped of colors.
+func excerptLines(log []string, line string, n int) ([]string, bool) {
+	line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+	if line == "" {
+		return nil, false
+	}
+	for i, l := range log {
+		if !strings.Contains(l, line) {
+			continue
+		}
+		start, end := i-n, i+n+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(log) {
+			end = len(log)
+		}
+		return log[start:end], true
+	}
+	return nil, false
+}
+
+// failureLine returns the first line of a match context naming test, or the
+// matched line, to find an extracted failure in the build log.
+func failureLine(match Match, test string) string {
+	for _, line := range match.Context {
+		if strings.Contains(ansiEscape.ReplaceAllString(line, ""), test) {
+			return line
+		}
+	}
+	if len(match.Context) == 0 {
+		return ""
+	}
+	return match.Context[len(match.Context)/2]
+}
@@ -0,0 +1,344 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Failure is a failed test found in a log excerpt.
+type Failure struct {
+	Test string
+	// Message is the first line of the failure message, if the excerpt
+	// holds one.
+	Message string
+	// Location is where the test failed, e.g. "foo_test.go:12" or, for kuttl,
+	// the failed step.
+	Location string
+}
+
+// FailureExtractor finds the failed tests in the lines of a search match,
+// the matched line and its context.
+type FailureExtractor interface {
+	// Name identifies the extractor in the config, e.g. "ginkgo".
+	Name() string
+	Extract(lines []string) []Failure
+}
+
+// KuttlExtractor finds kuttl harness test failures, named after the test
+// directory, with the failed step as location and its error as message.
+var KuttlExtractor FailureExtractor = kuttlExtractor{}
+
+// GinkgoExtractor finds Ginkgo v1 ([Fail]) and v2 ([FAIL], [FAILED]) spec
+// failures.
+var GinkgoExtractor FailureExtractor = ginkgoExtractor{}
+
+// GoTestExtractor finds go test failures. Of nested subtests only the
+// innermost failing ones are reported.
+var GoTestExtractor FailureExtractor = goTestExtractor{}
+
+// PytestExtractor finds the FAILED and ERROR tests of the pytest short
+// summary.
+var PytestExtractor FailureExtractor = pytestExtractor{}
+
+// Extractors are the built-in extractors by name.
+var Extractors = map[string]FailureExtractor{
+	KuttlExtractor.Name():  KuttlExtractor,
+	GinkgoExtractor.Name(): GinkgoExtractor,
+	GoTestExtractor.Name(): GoTestExtractor,
+	PytestExtractor.Name(): PytestExtractor,
+}
+
+// ExtractorByName returns the built-in extractor called name.
+func ExtractorByName(name string) (FailureExtractor, error) {
+	extractor, ok := Extractors[name]
+	if !ok {
+		names := []string{}
+		for n := range Extractors {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown extractor %q, must be one of %s", name, strings.Join(names, ", "))
+	}
+	return extractor, nil
+}
+
+// ansiEscape matches terminal color codes, which extractors ignore.
+var ansiEscape = regexp.MustCompile(ansiCommon)
+
+func stripColors(lines []string) []string {
+	clean := make([]string, len(lines))
+	for i, line := range lines {
+		clean[i] = ansiEscape.ReplaceAllString(line, "")
+	}
+	return clean
+}
+
+// failureList collects failures in order, merging the details of repeated
+// tests. The returned pointers stay valid while more failures are added.
+type failureList struct {
+	failures []*Failure
+	index    map[string]*Failure
+}
+
+func (l *failureList) add(f Failure) *Failure {
+	if l.index == nil {
+		l.index = map[string]*Failure{}
+	}
+	existing, ok := l.index[f.Test]
+	if !ok {
+		l.index[f.Test] = &f
+		l.failures = append(l.failures, &f)
+		return &f
+	}
+	if existing.Message == "" {
+		existing.Message = f.Message
+	}
+	if existing.Location == "" {
+		existing.Location = f.Location
+	}
+	return existing
+}
+
+func (l *failureList) get(test string) *Failure {
+	return l.index[test]
+}
+
+func (l *failureList) list() []Failure {
+	failures := []Failure{}
+	for _, f := range l.failures {
+		failures = append(failures, *f)
+	}
+	return failures
+}
+
+var (
+	// --- FAIL: TestFoo/sub_test (0.01s)
+	goTestFail = regexp.MustCompile(`^(\s*)--- FAIL: (\S+)(?: \([\d.]+m?s\))?`)
+	// foo_test.go:12: message
+	goTestLog = regexp.MustCompile(`^\s+(\S+\.go:\d+): (.*)$`)
+)
+
+type goTestExtractor struct{}
+
+func (goTestExtractor) Name() string {
+	return "gotest"
+}
+
+func (goTestExtractor) Extract(lines []string) []Failure {
+	list := failureList{}
+	var current *Failure
+	indent := -1
+	for _, line := range stripColors(lines) {
+		if m := goTestFail.FindStringSubmatch(line); m != nil {
+			current = list.add(Failure{Test: m[2]})
+			indent = len(m[1])
+			continue
+		}
+		// without -v, the log of a failed test follows its --- FAIL line,
+		// indented one level deeper
+		if m := goTestLog.FindStringSubmatch(line); m != nil && current != nil && leadingSpace(line) > indent {
+			if current.Message == "" {
+				current.Location, current.Message = m[1], strings.TrimSpace(m[2])
+			}
+			continue
+		}
+		if strings.TrimSpace(line) != "" && leadingSpace(line) <= indent {
+			current, indent = nil, -1
+		}
+	}
+	return innermostFailures(list.list())
+}
+
+// innermostFailures drops the tests whose subtests failed: their failure is
+// the subtest's.
+func innermostFailures(failures []Failure) []Failure {
+	leaves := []Failure{}
+	for _, f := range failures {
+		parent := false
+		for _, other := range failures {
+			if strings.HasPrefix(other.Test, f.Test+"/") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			leaves = append(leaves, f)
+		}
+	}
+	return leaves
+}
+
+func leadingSpace(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+const kuttlHarness = "kuttl/harness/"
+
+// logger.go:42: 10:45:03 | 1-085_validate_sync/2-check | test step failed 2-check
+var kuttlStepFailed = regexp.MustCompile(`\| ([^/\s|]+)/(\S+) \| test step failed`)
+
+type kuttlExtractor struct{}
+
+func (kuttlExtractor) Name() string {
+	return "kuttl"
+}
+
+func (kuttlExtractor) Extract(lines []string) []Failure {
+	lines = stripColors(lines)
+
+	// the step logs precede the --- FAIL lines: the failed step of a test and
+	// the first case log after it, its error
+	steps := map[string]string{}
+	stepErrors := map[string]string{}
+	failed := ""
+	for _, line := range lines {
+		if m := kuttlStepFailed.FindStringSubmatch(line); m != nil {
+			failed = m[1]
+			if _, ok := steps[failed]; !ok {
+				steps[failed] = m[2]
+			}
+			continue
+		}
+		if failed == "" {
+			continue
+		}
+		if kuttlLogger.MatchString(line) || kuttlTestResult.MatchString(line) {
+			failed = ""
+			continue
+		}
+		if m := kuttlCaseLog.FindStringSubmatch(line); m != nil && stepErrors[failed] == "" && !strings.HasPrefix(m[1], "failed in step") {
+			stepErrors[failed] = strings.TrimSpace(m[1])
+		}
+	}
+
+	// kuttl runs its tests as go subtests of kuttl/harness
+	list := failureList{}
+	for _, f := range (goTestExtractor{}).Extract(lines) {
+		test, ok := strings.CutPrefix(f.Test, kuttlHarness)
+		if !ok {
+			continue
+		}
+		failure := Failure{Test: test, Message: stepErrors[test], Location: steps[test]}
+		// without -v the step events follow the --- FAIL line, they are no
+		// error
+		if failure.Message == "" && !strings.HasPrefix(f.Location, "logger.go:") {
+			failure.Message = f.Message
+		}
+		list.add(failure)
+	}
+	return list.list()
+}
+
+var (
+	// v1 summary: [Fail] [sig-foo] Describe [It] does something
+	// v2 summary: [FAIL] [sig-foo] Describe [It] does something
+	ginkgoSummary = regexp.MustCompile(`^\s*\[(?:Fail|FAIL|PANICKED!|TIMEDOUT)\] (.+?)\s*$`)
+	// v2 spec report: • [FAILED] [0.002 seconds]
+	ginkgoSpecHeader = regexp.MustCompile(`^\s*• \[(?:FAILED|PANICKED!|TIMEDOUT)\]`)
+	// v2 failure message: [FAILED] Expected <int>: 1 to equal <int>: 2
+	ginkgoFailed = regexp.MustCompile(`^\s*\[(?:FAILED|PANICKED|TIMEDOUT)\] (.+?)\s*$`)
+	// v2 failure location: In [It] at: /go/src/foo_test.go:15 @ 01/02/24 10:00:00.000
+	ginkgoIn = regexp.MustCompile(`^\s*In \[[^\]]+\] at: (\S+:\d+)`)
+	// /go/src/github.com/org/repo/test/e2e/foo_test.go:123
+	ginkgoLocation = regexp.MustCompile(`^\s*(\S+\.go:\d+)\s*$`)
+)
+
+type ginkgoExtractor struct{}
+
+func (ginkgoExtractor) Name() string {
+	return "ginkgo"
+}
+
+func (ginkgoExtractor) Extract(lines []string) []Failure {
+	lines = stripColors(lines)
+	list := failureList{}
+
+	var spec *Failure
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// a failed spec report names the spec on the next line
+		if ginkgoSpecHeader.MatchString(line) {
+			spec = nil
+			if i+1 < len(lines) {
+				spec = list.add(Failure{Test: strings.TrimSpace(lines[i+1])})
+				i++
+			}
+			continue
+		}
+		if strings.Contains(line, "Summarizing ") {
+			spec = nil
+			continue
+		}
+
+		if spec != nil {
+			if m := ginkgoFailed.FindStringSubmatch(line); m != nil {
+				if spec.Message == "" {
+					spec.Message = m[1]
+				}
+				continue
+			}
+			if m := ginkgoIn.FindStringSubmatch(line); m != nil {
+				if spec.Location == "" {
+					spec.Location = m[1]
+				}
+				continue
+			}
+		}
+
+		if m := ginkgoSummary.FindStringSubmatch(line); m != nil {
+			f := list.add(Failure{Test: m[1]})
+			if i+1 < len(lines) {
+				if loc := ginkgoLocation.FindStringSubmatch(lines[i+1]); loc != nil && f.Location == "" {
+					f.Location = loc[1]
+				}
+			}
+		}
+	}
+	return list.list()
+}
+
+var (
+	// FAILED tests/test_foo.py::test_bar[param] - AssertionError: assert 1 == 2
+	pytestSummary = regexp.MustCompile(`^\s*(?:FAILED|ERROR) (\S+::\S+)(?: - (.*))?$`)
+	// ____________________ test_bar[param] ____________________
+	pytestHeadline = regexp.MustCompile(`^_{3,} (?:ERROR at \w+ of )?(\S+) _{3,}$`)
+	// tests/test_foo.py:12: AssertionError
+	pytestLocation = regexp.MustCompile(`^(\S+\.py:\d+): \w+`)
+)
+
+type pytestExtractor struct{}
+
+func (pytestExtractor) Name() string {
+	return "pytest"
+}
+
+func (pytestExtractor) Extract(lines []string) []Failure {
+	// the tracebacks precede the summary and only name the test function
+	locations := map[string]string{}
+	headline := ""
+	list := failureList{}
+	for _, line := range stripColors(lines) {
+		line = strings.TrimRight(line, " ")
+		if m := pytestHeadline.FindStringSubmatch(line); m != nil {
+			headline = m[1]
+			continue
+		}
+		if m := pytestLocation.FindStringSubmatch(line); m != nil && headline != "" {
+			locations[headline] = m[1]
+			continue
+		}
+		if m := pytestSummary.FindStringSubmatch(line); m != nil {
+			f := Failure{Test: m[1], Message: strings.TrimSpace(m[2])}
+			function := f.Test[strings.LastIndex(f.Test, "::")+2:]
+			f.Location = locations[function]
+			if f.Location == "" {
+				f.Location = f.Test[:strings.Index(f.Test, "::")]
+			}
+			list.add(f)
+		}
+	}
+	return list.list()
+}
@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractors(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor FailureExtractor
+		log       string
+		want      []Failure
+	}{
+		{
+			name:      "kuttl",
+			extractor: KuttlExtractor,
+			log: `    logger.go:42: 10:44:51 | 1-085_validate_sync/1-install | test step completed 1-install
+    logger.go:42: 10:44:51 | 1-085_validate_sync/2-check | starting test step 2-check
+    logger.go:42: 10:45:03 | 1-085_validate_sync/2-check | test step failed 2-check
+    case.go:364: failed in step 2-check
+    case.go:366: resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0
+    logger.go:42: 10:45:03 | 1-085_validate_sync | test-1-085 events from ns test-1-085:
+    logger.go:42: 10:45:03 | 1-085_validate_sync | 2023-11-14 10:44:52 +0000 UTC	Normal	Pod guestbook-6d8f	Scheduled	Successfully assigned
+    logger.go:42: 10:50:00 | 1-099_apply_manifest/1-apply | running command: [sh -c oc apply -f manifest.yaml -n $NAMESPACE]
+    logger.go:42: 10:50:01 | 1-099_apply_manifest/1-apply | error: resource mapping not found for name: "example" namespace: "" from "manifest.yaml"
+    logger.go:42: 10:50:01 | 1-099_apply_manifest/1-apply | command failure, skipping 1 additional commands
+    logger.go:42: 10:50:01 | 1-099_apply_manifest/1-apply | test step failed 1-apply
+    case.go:364: failed in step 1-apply
+    case.go:366: exit status 1
+=== CONT  kuttl
+    harness.go:405: run tests finished
+--- FAIL: kuttl (1234.56s)
+    --- FAIL: kuttl/harness (0.00s)
+        --- PASS: kuttl/harness/1-001_install (10.05s)
+        --- FAIL: kuttl/harness/1-085_validate_sync (120.37s)
+        --- FAIL: kuttl/harness/1-099_apply_manifest (1.02s)
+FAIL`,
+			want: []Failure{
+				{Test: "1-085_validate_sync", Message: "resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0", Location: "2-check"},
+				{Test: "1-099_apply_manifest", Message: "exit status 1", Location: "1-apply"},
+			},
+		},
+		{
+			name:      "kuttl without -v",
+			extractor: KuttlExtractor,
+			log: `--- FAIL: kuttl (1234.56s)
+    --- FAIL: kuttl/harness (0.00s)
+        --- FAIL: kuttl/harness/1-085_validate_sync (120.37s)
+            logger.go:42: 10:44:51 | 1-085_validate_sync/2-check | starting test step 2-check
+            logger.go:42: 10:45:03 | 1-085_validate_sync/2-check | test step failed 2-check
+            case.go:364: failed in step 2-check
+            case.go:366: resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0
+FAIL`,
+			want: []Failure{{Test: "1-085_validate_sync", Message: "resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0", Location: "2-check"}},
+		},
+		{
+			name:      "ginkgo v1",
+			extractor: GinkgoExtractor,
+			log: `Summarizing 2 Failures:
+
+[Fail] [sig-gitops] Argo CD [It] should create an instance
+/go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:123
+
+[Fail] [sig-gitops] Argo CD [It] should sync the application
+/go/src/github.com/redhat-developer/gitops-operator/test/e2e/sync_test.go:45
+
+Ran 12 of 12 Specs in 345.678 seconds
+FAIL! -- 10 Passed | 2 Failed | 0 Pending | 0 Skipped`,
+			want: []Failure{
+				{Test: "[sig-gitops] Argo CD [It] should create an instance", Location: "/go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:123"},
+				{Test: "[sig-gitops] Argo CD [It] should sync the application", Location: "/go/src/github.com/redhat-developer/gitops-operator/test/e2e/sync_test.go:45"},
+			},
+		},
+		{
+			name:      "ginkgo v2",
+			extractor: GinkgoExtractor,
+			log: "------------------------------\n" +
+				"\x1b[38;5;9m• [FAILED] [0.002 seconds]\x1b[0m\n" +
+				"[sig-gitops] Argo CD \x1b[38;5;9m\x1b[1m[It] should create an instance\x1b[0m\n" +
+				"/go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:15\n" +
+				"\n" +
+				"  \x1b[38;5;9m[FAILED] Expected\n" +
+				"      <int>: 1\n" +
+				"  to equal\n" +
+				"      <int>: 2\x1b[0m\n" +
+				"  \x1b[38;5;9mIn \x1b[1m[It]\x1b[0m\x1b[38;5;9m at: \x1b[1m/go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:17\x1b[0m @ 11/14/23 10:00:00.000\n" +
+				"------------------------------\n" +
+				"\n" +
+				"Summarizing 1 Failure:\n" +
+				"  [FAIL] [sig-gitops] Argo CD [It] should create an instance\n" +
+				"  /go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:17\n" +
+				"\n" +
+				"Ran 5 of 5 Specs in 0.010 seconds\n" +
+				"FAIL! -- 4 Passed | 1 Failed | 0 Pending | 0 Skipped",
+			want: []Failure{{
+				Test:     "[sig-gitops] Argo CD [It] should create an instance",
+				Message:  "Expected",
+				Location: "/go/src/github.com/redhat-developer/gitops-operator/test/e2e/argocd_test.go:17",
+			}},
+		},
+		{
+			name:      "go test subtests",
+			extractor: GoTestExtractor,
+			log: `=== RUN   TestReconcile
+=== RUN   TestReconcile/cluster_scoped
+=== RUN   TestReconcile/cluster_scoped/with_rbac
+=== RUN   TestReconcile/namespaced
+--- FAIL: TestReconcile (0.05s)
+    --- FAIL: TestReconcile/cluster_scoped (0.03s)
+        --- FAIL: TestReconcile/cluster_scoped/with_rbac (0.01s)
+            argocd_controller_test.go:88: expected role to exist: roles.rbac.authorization.k8s.io "argocd" not found
+            argocd_controller_test.go:90: second error
+    --- PASS: TestReconcile/namespaced (0.02s)
+=== RUN   TestOther
+--- FAIL: TestOther (0.00s)
+    other_test.go:12: boom
+FAIL
+FAIL	github.com/redhat-developer/gitops-operator/controllers	0.123s`,
+			want: []Failure{
+				{Test: "TestReconcile/cluster_scoped/with_rbac", Message: `expected role to exist: roles.rbac.authorization.k8s.io "argocd" not found`, Location: "argocd_controller_test.go:88"},
+				{Test: "TestOther", Message: "boom", Location: "other_test.go:12"},
+			},
+		},
+		{
+			name:      "pytest",
+			extractor: PytestExtractor,
+			log: `=================================== FAILURES ===================================
+________________________ test_sync[default-namespace] _________________________
+
+ns = 'default'
+
+    def test_sync(ns):
+>       assert app.status == "Synced"
+E       AssertionError: assert 'OutOfSync' == 'Synced'
+
+tests/e2e/test_sync.py:42: AssertionError
+_______________________ ERROR at setup of test_health ________________________
+
+    @pytest.fixture
+    def cluster():
+>       wait_for_cluster()
+E       TimeoutError
+
+tests/conftest.py:10: TimeoutError
+=========================== short test summary info ============================
+FAILED tests/e2e/test_sync.py::test_sync[default-namespace] - AssertionError: assert 'OutOfSync' == 'Synced'
+ERROR tests/e2e/test_health.py::test_health - TimeoutError
+FAILED tests/e2e/test_apps.py::TestApp::test_delete
+=================== 2 failed, 8 passed, 1 error in 12.34s ====================`,
+			want: []Failure{
+				{Test: "tests/e2e/test_sync.py::test_sync[default-namespace]", Message: "AssertionError: assert 'OutOfSync' == 'Synced'", Location: "tests/e2e/test_sync.py:42"},
+				{Test: "tests/e2e/test_health.py::test_health", Message: "TimeoutError", Location: "tests/conftest.py:10"},
+				{Test: "tests/e2e/test_apps.py::TestApp::test_delete", Location: "tests/e2e/test_apps.py"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.extractor.Extract(strings.Split(tt.log, "\n"))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s.Extract() =\n%#v\nwant\n%#v", tt.extractor.Name(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractorByName(t *testing.T) {
+	for name, extractor := range Extractors {
+		got, err := ExtractorByName(name)
+		if err != nil || got != extractor {
+			t.Errorf("ExtractorByName(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := ExtractorByName("junit"); err == nil {
+		t.Error("ExtractorByName(\"junit\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultConcurrency is the number of build logs fetched in parallel when
+// Config.Concurrency is not set.
+const defaultConcurrency = 8
+
+// fetchedRun is the outcome of fetching the build log of one search result.
+// The run is usable even when problems were recorded for it.
+type fetchedRun struct {
+	run      Run
+	problems []DataProblem
+}
+
+// concurrency is the number of workers of the pools fetching runs.
+func (a *Analyzer) concurrency() int {
+	if a.Concurrency <= 0 {
+		return defaultConcurrency
+	}
+	return a.Concurrency
+}
+
+// forEachIndex calls fn with every index below n through a pool of at most
+// a.concurrency() workers, and returns once all calls returned. Every index
+// is handled by exactly one worker, so fn may write to the index-th element
+// of a result slice without locking.
+func (a *Analyzer) forEachIndex(n int, fn func(index int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				fn(index)
+			}
+		}()
+	}
+
+	for index := 0; index < n; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// fetchRuns downloads the build logs of runs through the worker pool. Results
+// are returned in the order of runs, so the caller can aggregate them
+// deterministically on a single goroutine.
+func (a *Analyzer) fetchRuns(ctx context.Context, runs []Run) []fetchedRun {
+	results := make([]fetchedRun, len(runs))
+	a.forEachIndex(len(runs), func(index int) {
+		results[index] = a.fetchRun(ctx, runs[index])
+	})
+	return results
+}
+
+func (a *Analyzer) fetchRun(ctx context.Context, run Run) fetchedRun {
+	fetched := fetchedRun{run: run}
+	runURL := run.URL
+
+	prowRun, err := ParseProwURL(runURL)
+	if err != nil {
+		fetched.problems = append(fetched.problems, a.problem(StageURL, runURL, err))
+		return fetched
+	}
+	expectedBuildLogURL := prowRun.BuildLogURL(a.Config.StorageURL)
+	fetched.run.Prow = prowRun
+	fetched.run.BuildLogURL = expectedBuildLogURL
+
+	if err := ctx.Err(); err != nil {
+		fetched.problems = append(fetched.problems, a.problem(StageDownload, runURL, err))
+		return fetched
+	}
+
+	fetched.problems = append(fetched.problems, a.fetchMetadata(ctx, &fetched.run)...)
+	if a.Config.JUnit {
+		fetched.problems = append(fetched.problems, a.fetchJUnit(ctx, &fetched.run)...)
+	}
+
+	contents, err := downloadTestLog(ctx, a.storageClient(), runURL, *prowRun, a.Storage)
+	if err != nil {
+		fetched.problems = append(fetched.problems, a.problem(StageDownload, runURL, err))
+		fetched.run.Time = fetched.run.Started
+		return fetched
+	}
+	if a.classifier != nil {
+		fetched.run.Class, fetched.run.ClassRule = a.classifier.Classify(contents)
+	}
+	if a.Config.KuttlSteps {
+		fetched.run.KuttlSteps = ParseKuttlSteps(contents)
+	}
+
+	// started.json is authoritative, the build log is only a fallback
+	if fetched.run.Started != nil {
+		fetched.run.Time = fetched.run.Started
+		return fetched
+	}
+
+	runTime, err := parseRunTime(contents)
+	if err != nil {
+		fetched.problems = append(fetched.problems, a.problem(StageParse, runURL, err))
+		return fetched
+	}
+	fetched.run.Time = runTime
+
+	return fetched
+}
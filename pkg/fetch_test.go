@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachIndex(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 3} {
+		a := &Analyzer{Concurrency: concurrency}
+		want := concurrency
+		if want == 0 {
+			want = defaultConcurrency
+		}
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		calls := make([]int, 20)
+		a.forEachIndex(len(calls), func(index int) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+			calls[index]++
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+
+		for index, n := range calls {
+			if n != 1 {
+				t.Errorf("concurrency %d: index %d handled %d times, want once", concurrency, index, n)
+			}
+		}
+		if maxRunning > want {
+			t.Errorf("concurrency %d: %d calls ran at once, want at most %d", concurrency, maxRunning, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StorageClient reads objects from GCS, or any server speaking its JSON API
+// for listings and its XML API (GET/HEAD <endpoint>/<bucket>/<object>) for
+// downloads, such as fake-gcs-server.
+type StorageClient struct {
+	// BaseURL is the endpoint, DefaultStorageURL if empty.
+	BaseURL string
+	Client  *http.Client
+}
+
+// ObjectAttrs are the metadata of a stored object.
+type ObjectAttrs struct {
+	Name        string
+	Size        int64
+	Updated     time.Time
+	Generation  string
+	ETag        string
+	ContentType string
+}
+
+// ObjectList is the result of a listing: the objects directly below the
+// prefix and, with a delimiter, the "directories" below it.
+type ObjectList struct {
+	Objects  []ObjectAttrs
+	Prefixes []string
+}
+
+// gcsObjectList is a page of the GCS JSON API objects.list response.
+type gcsObjectList struct {
+	Items         []gcsObject `json:"items"`
+	Prefixes      []string    `json:"prefixes"`
+	NextPageToken string      `json:"nextPageToken"`
+}
+
+type gcsObject struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size,string,omitempty"`
+	Updated     time.Time `json:"updated,omitempty"`
+	Generation  string    `json:"generation,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+}
+
+// NewStorageClient returns a client for the storage endpoint at baseURL, or
+// DefaultStorageURL when it is empty.
+func NewStorageClient(baseURL string, client *http.Client) *StorageClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &StorageClient{BaseURL: storageBaseURL(baseURL), Client: client}
+}
+
+// ObjectURL is the download URL of an object.
+func (c *StorageClient) ObjectURL(bucket, name string) string {
+	return storageBaseURL(c.BaseURL) + "/" + bucket + "/" + name
+}
+
+// List returns the objects below prefix in bucket, following pagination.
+// With a delimiter, names containing it after the prefix are rolled up into
+// the returned prefixes, like directories.
+func (c *StorageClient) List(ctx context.Context, bucket, prefix, delimiter string) (*ObjectList, error) {
+	list := &ObjectList{Objects: []ObjectAttrs{}, Prefixes: []string{}}
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		listURL := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", storageBaseURL(c.BaseURL), url.PathEscape(bucket), query.Encode())
+
+		resp, err := c.do(ctx, "GET", listURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page gcsObjectList
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", listURL, err)
+		}
+
+		for _, item := range page.Items {
+			list.Objects = append(list.Objects, ObjectAttrs(item))
+		}
+		list.Prefixes = append(list.Prefixes, page.Prefixes...)
+		if page.NextPageToken == "" {
+			return list, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Attrs returns the metadata of an object without downloading it.
+func (c *StorageClient) Attrs(ctx context.Context, bucket, name string) (*ObjectAttrs, error) {
+	resp, err := c.do(ctx, "HEAD", c.ObjectURL(bucket, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectAttrs(name, resp), nil
+}
+
+// Get downloads an object.
+func (c *StorageClient) Get(ctx context.Context, bucket, name string) ([]byte, *ObjectAttrs, error) {
+	return c.get(ctx, bucket, name, nil)
+}
+
+// GetRange downloads length bytes of an object starting at offset. A negative
+// offset reads the last -offset bytes, and length is then ignored.
+func (c *StorageClient) GetRange(ctx context.Context, bucket, name string, offset, length int64) ([]byte, *ObjectAttrs, error) {
+	header := http.Header{}
+	if offset < 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d", offset))
+	} else {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+
+	data, attrs, err := c.get(ctx, bucket, name, header)
+	if err != nil || attrs.Size != int64(len(data)) {
+		return data, attrs, err
+	}
+
+	// the whole object was returned, the server ignored the range
+	switch {
+	case offset < 0 && int64(len(data)) > -offset:
+		data = data[int64(len(data))+offset:]
+	case offset >= int64(len(data)):
+		data = nil
+	case offset >= 0:
+		data = data[offset:]
+		if int64(len(data)) > length {
+			data = data[:length]
+		}
+	}
+	return data, attrs, nil
+}
+
+func (c *StorageClient) get(ctx context.Context, bucket, name string, header http.Header) ([]byte, *ObjectAttrs, error) {
+	objectURL := c.ObjectURL(bucket, name)
+	resp, err := c.do(ctx, "GET", objectURL, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("download %s: %w", objectURL, err)
+	}
+	attrs := objectAttrs(name, resp)
+	if resp.StatusCode == http.StatusOK {
+		attrs.Size = int64(len(data))
+	}
+	return data, attrs, nil
+}
+
+// do sends a request and turns 404 into errArtifactNotFound and any other
+// unexpected status into an error.
+func (c *StorageClient) do(ctx context.Context, method, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %w", strings.ToLower(method), rawURL, errArtifactNotFound)
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s %s: unexpected status %s", strings.ToLower(method), rawURL, resp.Status)
+}
+
+// objectAttrs reads the object metadata from the headers of an XML API
+// response.
+func objectAttrs(name string, resp *http.Response) *ObjectAttrs {
+	attrs := &ObjectAttrs{
+		Name:        name,
+		Size:        resp.ContentLength,
+		Generation:  resp.Header.Get("X-Goog-Generation"),
+		ETag:        resp.Header.Get("ETag"),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	// the full size of a partial response is in Content-Range: bytes 0-9/1234
+	if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				attrs.Size = size
+			}
+		}
+	}
+	if updated, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		attrs.Updated = updated.UTC()
+	}
+	return attrs
+}
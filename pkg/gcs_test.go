@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStorageClientGetRange(t *testing.T) {
+	const object = "0123456789"
+	modified := time.Date(2023, 11, 14, 10, 0, 0, 0, time.UTC)
+
+	// serves the object honoring Range requests with 206, like GCS
+	ranged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/build-log.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Goog-Generation", "1700000000000000")
+		w.Header().Set("ETag", `"abc"`)
+		http.ServeContent(w, r, "build-log.txt", modified, strings.NewReader(object))
+	}))
+	defer ranged.Close()
+	// serves the whole object with 200 whatever the Range header
+	whole := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write([]byte(object))
+	}))
+	defer whole.Close()
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{"head", 0, 4, "0123"},
+		{"middle", 3, 4, "3456"},
+		{"past the end", 8, 4, "89"},
+		{"tail", -3, 0, "789"},
+		{"tail longer than the object", -20, 0, object},
+	}
+	for _, server := range []*httptest.Server{ranged, whole} {
+		client := NewStorageClient(server.URL, server.Client())
+		for _, tt := range tests {
+			data, attrs, err := client.GetRange(context.Background(), "bucket", "build-log.txt", tt.offset, tt.length)
+			if err != nil {
+				t.Errorf("%s: GetRange(%d, %d): %v", tt.name, tt.offset, tt.length, err)
+				continue
+			}
+			if string(data) != tt.want {
+				t.Errorf("%s: GetRange(%d, %d) = %q, want %q", tt.name, tt.offset, tt.length, data, tt.want)
+			}
+			// the size is the full size, from Content-Range for a 206
+			if attrs.Size != int64(len(object)) || !attrs.Updated.Equal(modified) {
+				t.Errorf("%s: GetRange(%d, %d) attrs = %+v, want size %d updated %v", tt.name, tt.offset, tt.length, attrs, len(object), modified)
+			}
+		}
+	}
+
+	// the server ignoring the range returns nothing for a range past the end
+	client := NewStorageClient(whole.URL, whole.Client())
+	if data, _, err := client.GetRange(context.Background(), "bucket", "build-log.txt", 20, 4); err != nil || len(data) != 0 {
+		t.Errorf("GetRange(20, 4) = %q, %v, want nothing", data, err)
+	}
+
+	client = NewStorageClient(ranged.URL, ranged.Client())
+	if _, _, err := client.GetRange(context.Background(), "bucket", "missing.txt", 0, 4); !errors.Is(err, errArtifactNotFound) {
+		t.Errorf("GetRange(missing) error = %v, want errArtifactNotFound", err)
+	}
+}
+
+func TestStorageClientAttrs(t *testing.T) {
+	modified := time.Date(2023, 11, 14, 10, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("Attrs() sent %s, want HEAD", r.Method)
+		}
+		if r.URL.Path != "/bucket/logs/job/1/finished.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "1234")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Header().Set("X-Goog-Generation", "1700000000000000")
+		w.Header().Set("ETag", `"abc"`)
+	}))
+	defer server.Close()
+	client := NewStorageClient(server.URL, server.Client())
+
+	attrs, err := client.Attrs(context.Background(), "bucket", "logs/job/1/finished.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ObjectAttrs{
+		Name:        "logs/job/1/finished.json",
+		Size:        1234,
+		Updated:     modified,
+		Generation:  "1700000000000000",
+		ETag:        `"abc"`,
+		ContentType: "application/json",
+	}
+	if *attrs != want {
+		t.Errorf("Attrs() = %+v, want %+v", *attrs, want)
+	}
+
+	if _, err := client.Attrs(context.Background(), "bucket", "logs/job/2/finished.json"); !errors.Is(err, errArtifactNotFound) {
+		t.Errorf("Attrs(missing) error = %v, want errArtifactNotFound", err)
+	}
+}
@@ -0,0 +1,281 @@
+package pkg
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHistoryRuns bounds the number of runs enumerated per job.
+const maxHistoryRuns = 500
+
+// JobHistory summarizes every finished run of one job in the search window.
+// Aborted runs are neither counted as passed nor as failed.
+type JobHistory struct {
+	Job    string
+	Runs   int
+	Failed int
+	// Truncated is set when the job had more than maxHistoryRuns runs in
+	// the window and only the newest were counted.
+	Truncated bool
+	// Records are the counted runs, newest first.
+	Records []Run
+}
+
+// FailureRate is the share of counted runs that failed.
+func (h JobHistory) FailureRate() float64 {
+	if h.Runs == 0 {
+		return 0
+	}
+	return float64(h.Failed) / float64(h.Runs)
+}
+
+// runPassed reports whether a finished run passed. The second value is false
+// for runs that did not finish with a pass/fail result.
+func runPassed(run Run) (bool, bool) {
+	switch run.Result {
+	case "SUCCESS":
+		return true, true
+	case "FAILURE", "ERROR":
+		return false, true
+	}
+	return false, false
+}
+
+// jobHistories enumerates the runs of every job seen in runs.
+func (a *Analyzer) jobHistories(ctx context.Context, runs []Run) ([]JobHistory, []DataProblem) {
+	samples := map[string]ProwRun{}
+	for _, run := range runs {
+		if run.Prow != nil {
+			samples[run.Prow.Job] = *run.Prow
+		}
+	}
+
+	jobs := []string{}
+	for job := range samples {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	histories := []JobHistory{}
+	problems := []DataProblem{}
+	for _, job := range jobs {
+		history, jobProblems := a.jobHistory(ctx, samples[job])
+		histories = append(histories, history)
+		problems = append(problems, jobProblems...)
+	}
+	return histories, problems
+}
+
+// jobHistory walks the runs of the sample's job from newest to oldest until
+// they leave the search window.
+func (a *Analyzer) jobHistory(ctx context.Context, sample ProwRun) (JobHistory, []DataProblem) {
+	history := JobHistory{Job: sample.Job}
+	jobURL := storageBaseURL(a.Config.StorageURL) + "/" + sample.Bucket + "/" + path.Dir(sample.Path())
+
+	candidates, err := a.listJobRuns(ctx, sample)
+	if err != nil {
+		return history, []DataProblem{a.problem(StageHistory, jobURL, err)}
+	}
+
+	problems := []DataProblem{}
+	cutoff := time.Now().Add(-time.Duration(a.Config.MaxAge))
+
+	// resolve and fetch a pool-sized batch at a time, so at most one batch
+	// is fetched past the end of the window
+	concurrency := a.concurrency()
+	for start := 0; start < len(candidates); start += concurrency {
+		end := start + concurrency
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+
+		batch := make([]Run, end-start)
+		batchProblems := make([][]DataProblem, end-start)
+		a.forEachIndex(len(batch), func(i int) {
+			run, runProblems := a.historyRun(ctx, candidates[start+i])
+			// the junit outcomes of every counted run are the denominator
+			// of the junit counts
+			_, finished := runPassed(run)
+			if a.Config.JUnit && finished && (run.Started == nil || !run.Started.Before(cutoff)) {
+				runProblems = append(runProblems, a.fetchJUnit(ctx, &run)...)
+			}
+			batch[i], batchProblems[i] = run, runProblems
+		})
+
+		for i, run := range batch {
+			problems = append(problems, batchProblems[i]...)
+			if run.Started != nil && run.Started.Before(cutoff) {
+				return history, problems
+			}
+			passed, finished := runPassed(run)
+			if !finished {
+				continue
+			}
+			if history.Runs == maxHistoryRuns {
+				history.Truncated = true
+				return history, problems
+			}
+			history.Runs++
+			if !passed {
+				history.Failed++
+			}
+			history.Records = append(history.Records, run)
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return history, problems
+}
+
+// historyRun resolves the link of one enumerated run, if any, and fetches its
+// prow metadata. A run whose link cannot be resolved is returned empty, so it
+// is not counted.
+func (a *Analyzer) historyRun(ctx context.Context, candidate historyCandidate) (Run, []DataProblem) {
+	prowRun := candidate.run
+	if candidate.link != "" {
+		linkURL := a.storageClient().ObjectURL(prowRun.Bucket, candidate.link)
+		if err := ctx.Err(); err != nil {
+			return Run{}, []DataProblem{a.problem(StageHistory, linkURL, err)}
+		}
+		resolved, err := a.resolveRunLink(ctx, prowRun.Bucket, candidate.link)
+		if err != nil {
+			return Run{}, []DataProblem{a.problem(StageHistory, linkURL, err)}
+		}
+		prowRun = *resolved
+	}
+
+	run := Run{
+		URL:         prowRun.ViewURL(),
+		Prow:        &prowRun,
+		JobName:     prowRun.Job,
+		BuildLogURL: prowRun.BuildLogURL(a.Config.StorageURL),
+	}
+	if err := ctx.Err(); err != nil {
+		return run, []DataProblem{a.problem(StageHistory, run.URL, err)}
+	}
+
+	problems := a.fetchMetadata(ctx, &run)
+	run.Time = run.Started
+	return run, problems
+}
+
+// historyCandidate is an enumerated run of a job. Presubmit runs are only
+// known by the pr-logs/directory link to their artifacts until historyRun
+// resolves it.
+type historyCandidate struct {
+	run ProwRun
+	// link is the name of the object holding the URL of the run, if any.
+	link string
+}
+
+// listJobRuns returns the runs of the sample's job, newest first. Periodic
+// and postsubmit runs are listed from logs/<job>/; presubmit runs of all PRs
+// are found through the pr-logs/directory/<job>/<build id>.txt links prow
+// writes next to them.
+func (a *Analyzer) listJobRuns(ctx context.Context, sample ProwRun) ([]historyCandidate, error) {
+	candidates := []historyCandidate{}
+	runs := []ProwRun{}
+	storage := a.storageClient()
+
+	if sample.PR == 0 && !sample.Batch {
+		list, err := storage.List(ctx, sample.Bucket, "logs/"+sample.Job+"/", "/")
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range list.Prefixes {
+			buildID := path.Base(prefix)
+			if _, err := strconv.ParseUint(buildID, 10, 64); err != nil {
+				continue
+			}
+			runs = append(runs, ProwRun{Bucket: sample.Bucket, Job: sample.Job, BuildID: buildID})
+		}
+		sortRunsNewestFirst(runs)
+		for _, run := range runs {
+			candidates = append(candidates, historyCandidate{run: run})
+		}
+		return candidates, nil
+	}
+
+	list, err := storage.List(ctx, sample.Bucket, "pr-logs/directory/"+sample.Job+"/", "/")
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range list.Objects {
+		buildID := strings.TrimSuffix(path.Base(object.Name), ".txt")
+		if _, err := strconv.ParseUint(buildID, 10, 64); err != nil {
+			// latest-build.txt
+			continue
+		}
+		runs = append(runs, ProwRun{Bucket: sample.Bucket, Job: sample.Job, BuildID: buildID})
+	}
+	sortRunsNewestFirst(runs)
+	for _, run := range runs {
+		candidates = append(candidates, historyCandidate{run: run, link: "pr-logs/directory/" + run.Job + "/" + run.BuildID + ".txt"})
+	}
+	return candidates, nil
+}
+
+// resolveRunLink reads the run a pr-logs/directory link points to.
+func (a *Analyzer) resolveRunLink(ctx context.Context, bucket, link string) (*ProwRun, error) {
+	contents, err := downloadArtifact(ctx, a.storageClient(), bucket, link, a.Storage)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProwURL(strings.TrimSpace(string(contents)))
+}
+
+// sortRunsNewestFirst orders runs by descending build id. Prow build ids
+// increase monotonically.
+func sortRunsNewestFirst(runs []ProwRun) {
+	sort.Slice(runs, func(i, j int) bool {
+		one, _ := strconv.ParseUint(runs[i].BuildID, 10, 64)
+		two, _ := strconv.ParseUint(runs[j].BuildID, 10, 64)
+		return one > two
+	})
+}
+
+// testRunRate returns the number of distinct runs a test failed in and the
+// number of finished runs of the jobs it failed in, according to histories.
+// Both are 0 when none of the jobs has a history.
+func testRunRate(groups []RunGroup, histories []JobHistory) (int, int) {
+	byJob := map[string]JobHistory{}
+	for _, history := range histories {
+		byJob[history.Job] = history
+	}
+
+	failedRuns := map[string]map[string]bool{}
+	for _, group := range groups {
+		for _, run := range group.Runs {
+			if run.Prow == nil {
+				continue
+			}
+			if _, ok := byJob[run.Prow.Job]; !ok {
+				continue
+			}
+			if failedRuns[run.Prow.Job] == nil {
+				failedRuns[run.Prow.Job] = map[string]bool{}
+			}
+			failedRuns[run.Prow.Job][run.Prow.BuildID] = true
+		}
+	}
+
+	failed, total := 0, 0
+	for job, builds := range failedRuns {
+		jobFailed := len(builds)
+		if jobFailed > byJob[job].Runs {
+			// failures seen by search in runs the history did not count,
+			// e.g. runs that are still in progress
+			jobFailed = byJob[job].Runs
+		}
+		failed += jobFailed
+		total += byJob[job].Runs
+	}
+	return failed, total
+}
@@ -0,0 +1,271 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGCS serves objects through the GCS JSON list API, two per page, and
+// the XML download API, and records the downloaded object names.
+type fakeGCS struct {
+	objects map[string]string
+
+	mu         sync.Mutex
+	downloaded map[string]bool
+}
+
+func newFakeGCS(t *testing.T, objects map[string]string) (*fakeGCS, *httptest.Server) {
+	fake := &fakeGCS{objects: objects, downloaded: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if bucket, ok := strings.CutPrefix(r.URL.Path, "/storage/v1/b/"); ok {
+		f.list(w, r, strings.TrimSuffix(bucket, "/o"))
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	f.mu.Lock()
+	f.downloaded[name] = true
+	f.mu.Unlock()
+	contents, ok := f.objects[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, contents)
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix := bucket + "/" + r.URL.Query().Get("prefix")
+	delimiter := r.URL.Query().Get("delimiter")
+
+	// objects and prefixes in name order, like GCS
+	entries := []string{}
+	seen := map[string]bool{}
+	for name := range f.objects {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		entry := name
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			entry = prefix + rest[:i+len(delimiter)]
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	sort.Strings(entries)
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	end := start + 2
+	page := gcsObjectList{}
+	if end < len(entries) {
+		page.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(entries)
+	}
+	for _, entry := range entries[start:end] {
+		name := strings.TrimPrefix(entry, bucket+"/")
+		if _, ok := f.objects[entry]; !ok {
+			page.Prefixes = append(page.Prefixes, name)
+			continue
+		}
+		page.Items = append(page.Items, gcsObject{Name: name, Size: int64(len(f.objects[entry]))})
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func (f *fakeGCS) wasDownloaded(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.downloaded[name]
+}
+
+func historyAnalyzer(t *testing.T, server *httptest.Server, kind JobKind) *Analyzer {
+	storage, err := NewBlobStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Analyzer{
+		Config:      Config{StorageURL: server.URL, MaxAge: Duration(24 * time.Hour)},
+		Kind:        kind,
+		Storage:     *storage,
+		HTTPClient:  server.Client(),
+		Concurrency: 2,
+	}
+}
+
+// addHistoryRun stores the metadata of a run started age ago; an empty
+// result leaves the run unfinished.
+func addHistoryRun(objects map[string]string, run ProwRun, age time.Duration, result string) {
+	prefix := run.Bucket + "/" + run.Path() + "/"
+	objects[prefix+"started.json"] = fmt.Sprintf(`{"timestamp": %d}`, time.Now().Add(-age).Unix())
+	if result != "" {
+		objects[prefix+"finished.json"] = fmt.Sprintf(`{"timestamp": %d, "result": %q}`, time.Now().Add(-age).Add(time.Hour).Unix(), result)
+	}
+}
+
+func historyBuildIDs(history JobHistory) []string {
+	ids := []string{}
+	for _, run := range history.Records {
+		ids = append(ids, run.Prow.BuildID)
+	}
+	return ids
+}
+
+func TestJobHistoryPeriodic(t *testing.T) {
+	const job = "periodic-ci-redhat-developer-gitops-operator-master-nightly"
+	objects := map[string]string{}
+	run := func(id string) ProwRun {
+		return ProwRun{Bucket: "test-platform-results", Job: job, BuildID: id}
+	}
+	addHistoryRun(objects, run("106"), time.Hour, "")
+	addHistoryRun(objects, run("105"), 2*time.Hour, "SUCCESS")
+	addHistoryRun(objects, run("104"), 3*time.Hour, "FAILURE")
+	addHistoryRun(objects, run("103"), 4*time.Hour, "ABORTED")
+	addHistoryRun(objects, run("102"), 5*time.Hour, "ERROR")
+	// outside the window
+	addHistoryRun(objects, run("101"), 48*time.Hour, "FAILURE")
+	addHistoryRun(objects, run("100"), 72*time.Hour, "FAILURE")
+	addHistoryRun(objects, run("99"), 96*time.Hour, "FAILURE")
+	objects["test-platform-results/logs/"+job+"/latest-build.txt"] = "106"
+
+	fake, server := newFakeGCS(t, objects)
+	a := historyAnalyzer(t, server, PeriodicJobs)
+
+	candidates, err := a.listJobRuns(context.Background(), run("105"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the listing spans several pages and build ids sort numerically
+	if len(candidates) != 8 || candidates[0].run.BuildID != "106" || candidates[7].run.BuildID != "99" {
+		t.Fatalf("listJobRuns() = %+v, want the 8 runs from 106 to 99", candidates)
+	}
+
+	history, problems := a.jobHistory(context.Background(), run("105"))
+	if len(problems) != 0 {
+		t.Errorf("jobHistory() problems = %+v", problems)
+	}
+	if got := historyBuildIDs(history); strings.Join(got, ",") != "105,104,102" {
+		t.Errorf("jobHistory() records = %v, want the finished runs 105, 104, 102", got)
+	}
+	if history.Runs != 3 || history.Failed != 2 || history.Truncated {
+		t.Errorf("jobHistory() = %d runs, %d failed, truncated %v, want 3, 2, false", history.Runs, history.Failed, history.Truncated)
+	}
+	// the batch holding the first run out of the window is the last fetched
+	if fake.wasDownloaded("test-platform-results/logs/" + job + "/99/started.json") {
+		t.Error("jobHistory() fetched a run past the batch leaving the window")
+	}
+}
+
+func TestJobHistoryPresubmit(t *testing.T) {
+	const job = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	objects := map[string]string{}
+	link := func(id string, run ProwRun) {
+		objects["test-platform-results/pr-logs/directory/"+job+"/"+id+".txt"] = run.ViewURL() + "\n"
+	}
+	run := func(pr int, id string) ProwRun {
+		return ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: pr, Job: job, BuildID: id}
+	}
+	for _, r := range []struct {
+		run    ProwRun
+		age    time.Duration
+		result string
+	}{
+		{run(12, "205"), time.Hour, "FAILURE"},
+		{run(11, "203"), 3 * time.Hour, "SUCCESS"},
+		{run(10, "202"), 4 * time.Hour, "FAILURE"},
+		{run(10, "201"), 48 * time.Hour, "FAILURE"},
+		{run(9, "200"), 72 * time.Hour, "FAILURE"},
+		{run(9, "199"), 96 * time.Hour, "FAILURE"},
+	} {
+		addHistoryRun(objects, r.run, r.age, r.result)
+		link(r.run.BuildID, r.run)
+	}
+	// a broken link is a problem, not the end of the history
+	objects["test-platform-results/pr-logs/directory/"+job+"/204.txt"] = "not a prow URL"
+	objects["test-platform-results/pr-logs/directory/"+job+"/latest-build.txt"] = "205"
+
+	fake, server := newFakeGCS(t, objects)
+	a := historyAnalyzer(t, server, PullJobs)
+
+	history, problems := a.jobHistory(context.Background(), run(12, "205"))
+	if len(problems) != 1 || problems[0].Stage != StageHistory || !strings.HasSuffix(problems[0].RunURL, "/204.txt") {
+		t.Errorf("jobHistory() problems = %+v, want one for the link of 204", problems)
+	}
+	if got := historyBuildIDs(history); strings.Join(got, ",") != "205,203,202" {
+		t.Errorf("jobHistory() records = %v, want 205, 203, 202", got)
+	}
+	if history.Runs != 3 || history.Failed != 2 {
+		t.Errorf("jobHistory() = %d runs, %d failed, want 3, 2", history.Runs, history.Failed)
+	}
+	if len(history.Records) > 1 && history.Records[1].Prow.PR != 11 {
+		t.Errorf("jobHistory() resolved 203 to PR %d, want 11", history.Records[1].Prow.PR)
+	}
+	// links are resolved batch by batch: 201 leaves the window in the
+	// batch of 200, and 199 is never resolved
+	if fake.wasDownloaded("test-platform-results/pr-logs/directory/" + job + "/199.txt") {
+		t.Error("jobHistory() resolved a link past the batch leaving the window")
+	}
+}
+
+func TestJobHistoryJUnit(t *testing.T) {
+	const job = "periodic-ci-redhat-developer-gitops-operator-master-nightly"
+	objects := map[string]string{}
+	run := func(id string) ProwRun {
+		return ProwRun{Bucket: "test-platform-results", Job: job, BuildID: id}
+	}
+	junit := func(id, testcase string) {
+		objects["test-platform-results/"+run(id).Path()+"/artifacts/e2e/kuttl/artifacts/junit_kuttl.xml"] = `<testsuite name="kuttl">` + testcase + `</testsuite>`
+	}
+	passed := `<testcase name="harness/1-085_validate_sync"/>`
+	failed := `<testcase name="harness/1-085_validate_sync"><failure message="value mismatch"/></testcase>`
+	addHistoryRun(objects, run("104"), time.Hour, "SUCCESS")
+	junit("104", passed)
+	addHistoryRun(objects, run("103"), 2*time.Hour, "FAILURE")
+	junit("103", failed)
+	addHistoryRun(objects, run("102"), 3*time.Hour, "SUCCESS")
+	junit("102", passed)
+	// outside the window
+	addHistoryRun(objects, run("101"), 48*time.Hour, "FAILURE")
+	junit("101", failed)
+
+	fake, server := newFakeGCS(t, objects)
+	a := historyAnalyzer(t, server, PeriodicJobs)
+	a.Config.JUnit = true
+
+	history, problems := a.jobHistory(context.Background(), run("103"))
+	if len(problems) != 0 {
+		t.Errorf("jobHistory() problems = %+v", problems)
+	}
+	if fake.wasDownloaded("test-platform-results/" + run("101").Path() + "/artifacts/e2e/kuttl/artifacts/junit_kuttl.xml") {
+		t.Error("jobHistory() fetched the junit files of a run out of the window")
+	}
+
+	// the failed run found by search is counted once, through the history
+	found := Run{URL: run("103").ViewURL(), Prow: &ProwRun{Bucket: "test-platform-results", Job: job, BuildID: "103"}}
+	other := ProwRun{Bucket: "test-platform-results", Job: "periodic-other", BuildID: "1"}
+	stats := junitStats(junitRuns([]Run{found, {Prow: &other, JUnit: []TestCaseResult{{Name: "harness/1-001_install", Status: TestFailed}}}}, []JobHistory{history}))
+	want := []JUnitStats{
+		{Name: "harness/1-001_install", Failed: 1},
+		{Name: "harness/1-085_validate_sync", Failed: 1, Passed: 2},
+	}
+	if len(stats) != 2 || stats[0] != want[0] || stats[1] != want[1] {
+		t.Errorf("junitStats() = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// RunClass tells whether a run failed because of the CI infrastructure or
+// of its tests.
+type RunClass string
+
+const (
+	RunInfra   RunClass = "infra"
+	RunTest    RunClass = "test"
+	RunUnknown RunClass = "unknown"
+)
+
+// What to do with the failures found in infra runs, see
+// Config.InfraFailures.
+const (
+	InfraCount   = "count"
+	InfraExclude = "exclude"
+)
+
+// ClassifierRule labels a run whose build log matches Regex with Class.
+type ClassifierRule struct {
+	Name  string   `json:"name"`
+	Regex string   `json:"regex"`
+	Class RunClass `json:"class"`
+}
+
+// defaultClassifierRules follow the configured rules. The first matching
+// rule wins, so the infra signatures come before the test failures they
+// cause. The quota and image pull rules only match ci-operator step failures
+// and installer errors: the events a test dumps, e.g. the ImagePullBackOff of
+// a pod it deploys, must not turn its failure into an infra one.
+var defaultClassifierRules = []ClassifierRule{
+	{Name: "lease", Regex: `(?i)failed to acquire lease`, Class: RunInfra},
+	{Name: "quota", Regex: `(?:step \S+ failed|level=(?:error|fatal)).*(?:(?i:quota \S* ?exceeded)|QuotaExceeded|LimitExceeded|InsufficientInstanceCapacity|exceeded quota)`, Class: RunInfra},
+	{Name: "cluster install", Regex: `Step \S*(?:ipi-install|upi-install|ipi-conf|cluster-install|install-install)\S* failed`, Class: RunInfra},
+	{Name: "release import", Regex: `could not resolve inputs|failed to import release|could not (?:import|resolve) (?:release|image)`, Class: RunInfra},
+	{Name: "image pull", Regex: `(?:step \S+ failed|pod pending for more than|could not (?:import|resolve|run)).*(?:ErrImagePull|ImagePullBackOff|failed to pull image|manifest unknown)`, Class: RunInfra},
+	{Name: "ci pod", Regex: `pod pending for more than|the pod \S+ was deleted|failed to create or restart \S+ pod|could not run steps: step \S+ failed: .*(?:pending|deleted|evicted)`, Class: RunInfra},
+	{Name: "test failure", Regex: `--- FAIL: |\[(?:Fail|FAIL)\] |^FAILED |Step \S+ failed`, Class: RunTest},
+}
+
+var builtinClassifierRules = mustClassifierRules(defaultClassifierRules)
+
+type classifierRule struct {
+	ClassifierRule
+	regex *regexp.Regexp
+}
+
+// RunClassifier labels runs by the first rule matching their build log.
+type RunClassifier struct {
+	rules []classifierRule
+}
+
+// NewRunClassifier compiles rules, followed by the default rules.
+func NewRunClassifier(rules []ClassifierRule) (*RunClassifier, error) {
+	compiled, err := compileClassifierRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &RunClassifier{rules: append(compiled, builtinClassifierRules...)}, nil
+}
+
+func compileClassifierRules(rules []ClassifierRule) ([]classifierRule, error) {
+	compiled := []classifierRule{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("infra rule %q: name is required", rule.Regex)
+		}
+		if rule.Class != RunInfra && rule.Class != RunTest {
+			return nil, fmt.Errorf("infra rule %s: class %q must be %q or %q", rule.Name, rule.Class, RunInfra, RunTest)
+		}
+		// ^ and $ match at line boundaries of the build log
+		re, err := regexp.Compile("(?m)" + rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("infra rule %s: invalid regex: %w", rule.Name, err)
+		}
+		compiled = append(compiled, classifierRule{rule, re})
+	}
+	return compiled, nil
+}
+
+func mustClassifierRules(rules []ClassifierRule) []classifierRule {
+	compiled, err := compileClassifierRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// Classify returns the class of a run and the name of the rule that
+// matched its build log, RunUnknown without a match.
+func (c *RunClassifier) Classify(buildLog string) (RunClass, string) {
+	for _, rule := range c.rules {
+		if rule.regex.MatchString(buildLog) {
+			return rule.Class, rule.Name
+		}
+	}
+	return RunUnknown, ""
+}
+
+// InfraSummary counts the infra runs of a job among its fetched runs.
+type InfraSummary struct {
+	Job   string
+	Runs  int
+	Infra int
+	// Reasons are the rules that classified the infra runs, most frequent
+	// first.
+	Reasons []DimensionCount
+}
+
+// infraSummaries returns the jobs with infra runs, most infra runs first.
+func infraSummaries(runs []Run) []InfraSummary {
+	byJob := map[string]*InfraSummary{}
+	reasons := map[string]map[string]int{}
+	for _, run := range runs {
+		summary, ok := byJob[run.JobName]
+		if !ok {
+			summary = &InfraSummary{Job: run.JobName}
+			byJob[run.JobName] = summary
+			reasons[run.JobName] = map[string]int{}
+		}
+		summary.Runs++
+		if run.Class == RunInfra {
+			summary.Infra++
+			reasons[run.JobName][run.ClassRule]++
+		}
+	}
+
+	all := []InfraSummary{}
+	for job, summary := range byJob {
+		if summary.Infra == 0 {
+			continue
+		}
+		summary.Reasons = dimensionCounts(reasons[job])
+		all = append(all, *summary)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Infra != all[j].Infra {
+			return all[i].Infra > all[j].Infra
+		}
+		return all[i].Job < all[j].Job
+	})
+	return all
+}
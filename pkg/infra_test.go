@@ -0,0 +1,98 @@
+package pkg
+
+import "testing"
+
+// kuttlImagePullLog is a kuttl test failing its assertion, whose namespace
+// events include the image pull errors of the deployment under test.
+const kuttlImagePullLog = `INFO[2023-11-14T10:30:00Z] Running step e2e-kuttl-test.
+    logger.go:42: 10:44:51 | 1-085_validate_sync/2-check | starting test step 2-check
+    logger.go:42: 10:45:03 | 1-085_validate_sync/2-check | test step failed 2-check
+    case.go:366: resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0
+    logger.go:42: 10:45:03 | 1-085_validate_sync | test-1-085 events from ns test-1-085:
+    logger.go:42: 10:45:03 | 1-085_validate_sync | 2023-11-14 10:44:52 +0000 UTC	Warning	Pod guestbook-6d8f		Failed	Failed to pull image "quay.io/example/guestbook:missing": rpc error: code = Unknown desc = reading manifest missing in quay.io/example/guestbook: manifest unknown
+    logger.go:42: 10:45:03 | 1-085_validate_sync | 2023-11-14 10:44:52 +0000 UTC	Warning	Pod guestbook-6d8f		Failed	Error: ErrImagePull
+    logger.go:42: 10:45:03 | 1-085_validate_sync | 2023-11-14 10:44:53 +0000 UTC	Warning	Pod guestbook-6d8f		Failed	Error: ImagePullBackOff
+    logger.go:42: 10:45:03 | 1-085_validate_sync | 2023-11-14 10:44:54 +0000 UTC	Warning	ResourceQuota compute		FailedCreate	exceeded quota: compute, requested: cpu=2
+--- FAIL: kuttl (1234.56s)
+    --- FAIL: kuttl/harness (0.00s)
+        --- FAIL: kuttl/harness/1-085_validate_sync (120.37s)
+FAIL
+INFO[2023-11-14T11:00:00Z] Step e2e-kuttl-test failed after 30m0s.
+error: some steps failed:
+  * could not run steps: step e2e-kuttl failed: "e2e-kuttl" test steps failed: "e2e-kuttl" pod "e2e-kuttl-test" failed: the pod ci-op-abc/e2e-kuttl-test failed after 30m0s (failed containers: test): ContainerFailed one or more containers exited`
+
+func TestRunClassifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []ClassifierRule
+		log      string
+		class    RunClass
+		ruleName string
+	}{
+		{
+			name:     "image pull events of a failing kuttl test",
+			log:      kuttlImagePullLog,
+			class:    RunTest,
+			ruleName: "test failure",
+		},
+		{
+			name: "ci-operator image pull",
+			log: `INFO[2023-11-14T10:30:00Z] Running step e2e-kuttl-test.
+error: some steps failed:
+  * could not run steps: step e2e-kuttl failed: "e2e-kuttl" test steps failed: "e2e-kuttl" pod "e2e-kuttl-test" failed: pod pending for more than 1h0m0s: containers have not started in 1h0m0.004s: test: ImagePullBackOff`,
+			class:    RunInfra,
+			ruleName: "image pull",
+		},
+		{
+			name: "installer quota",
+			log: `level=info msg=Creating infrastructure resources...
+level=error msg=Error: creating EC2 Instance: InsufficientInstanceCapacity: We currently do not have sufficient m6a.xlarge capacity
+INFO[2023-11-14T10:40:00Z] Step e2e-aws-ipi-install-install failed after 40m0s.`,
+			class:    RunInfra,
+			ruleName: "quota",
+		},
+		{
+			name:     "lease",
+			log:      `error: failed to acquire lease for "aws-quota-slice": resources not found`,
+			class:    RunInfra,
+			ruleName: "lease",
+		},
+		{
+			name:     "configured rules come first",
+			rules:    []ClassifierRule{{Name: "guestbook image", Regex: `quay\.io/example/guestbook:missing`, Class: RunInfra}},
+			log:      kuttlImagePullLog,
+			class:    RunInfra,
+			ruleName: "guestbook image",
+		},
+		{
+			name:  "no match",
+			log:   "INFO[2023-11-14T10:30:00Z] Running step e2e-kuttl-test.",
+			class: RunUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifier, err := NewRunClassifier(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			class, rule := classifier.Classify(tt.log)
+			if class != tt.class || rule != tt.ruleName {
+				t.Errorf("Classify() = %q, %q, want %q, %q", class, rule, tt.class, tt.ruleName)
+			}
+		})
+	}
+}
+
+func TestNewRunClassifierInvalid(t *testing.T) {
+	for _, rule := range []ClassifierRule{
+		{Regex: "foo", Class: RunInfra},
+		{Name: "foo", Regex: "foo", Class: RunUnknown},
+		{Name: "foo", Regex: "(", Class: RunTest},
+	} {
+		if _, err := NewRunClassifier([]ClassifierRule{rule}); err == nil {
+			t.Errorf("NewRunClassifier(%+v) succeeded, want an error", rule)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultBranches are searched when a target does not list its branches.
+var DefaultBranches = []string{"master", "main"}
+
+// DefaultExcludeJobs are skipped when a target does not list exclusions.
+var DefaultExcludeJobs = []string{"*rehearse*"}
+
+// jobPattern matches a job name. Patterns are globs ("*-e2e-*") unless they
+// are wrapped in slashes, in which case they are regexes ("/-v4\.1[0-9]-/").
+type jobPattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+func newJobPattern(pattern string) (jobPattern, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return jobPattern{}, fmt.Errorf("invalid job pattern %q: %w", pattern, err)
+		}
+		return jobPattern{regex: re}, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return jobPattern{}, fmt.Errorf("invalid job pattern %q: %w", pattern, err)
+	}
+	return jobPattern{glob: pattern}, nil
+}
+
+func (p jobPattern) match(jobName string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(jobName)
+	}
+	matched, _ := path.Match(p.glob, jobName)
+	return matched
+}
+
+// JobFilter selects the jobs of a target by branch and include/exclude
+// patterns.
+type JobFilter struct {
+	branches []string
+	include  []jobPattern
+	exclude  []jobPattern
+}
+
+// NewJobFilter ...
+func NewJobFilter(config Config) (*JobFilter, error) {
+	filter := &JobFilter{branches: config.Branches}
+	if len(filter.branches) == 0 {
+		filter.branches = DefaultBranches
+	}
+	// longest first, so "release-4.14" wins over "release"
+	filter.branches = append([]string{}, filter.branches...)
+	sort.SliceStable(filter.branches, func(i, j int) bool {
+		return len(filter.branches[i]) > len(filter.branches[j])
+	})
+
+	for _, p := range config.IncludeJobs {
+		pattern, err := newJobPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		filter.include = append(filter.include, pattern)
+	}
+
+	exclude := config.ExcludeJobs
+	if exclude == nil {
+		exclude = DefaultExcludeJobs
+	}
+	for _, p := range exclude {
+		pattern, err := newJobPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		filter.exclude = append(filter.exclude, pattern)
+	}
+
+	return filter, nil
+}
+
+// SearchName is the search.ci job name regex for jobs starting with prefix on
+// any of the filter's branches.
+func (f *JobFilter) SearchName(prefix string) string {
+	branches := []string{}
+	for _, branch := range f.branches {
+		branches = append(branches, regexp.QuoteMeta(branch))
+	}
+	return fmt.Sprintf("^%s(%s)-", regexp.QuoteMeta(prefix), strings.Join(branches, "|"))
+}
+
+// Branch returns the branch of a job named <prefix><branch>-..., or "".
+func (f *JobFilter) Branch(prefix, jobName string) string {
+	rest, ok := strings.CutPrefix(jobName, prefix)
+	if !ok {
+		return ""
+	}
+	for _, branch := range f.branches {
+		if strings.HasPrefix(rest, branch+"-") {
+			return branch
+		}
+	}
+	return ""
+}
+
+// Match reports whether the job is selected: it must be on one of the
+// branches, match an include pattern when there are any, and match no
+// exclude pattern.
+func (f *JobFilter) Match(prefix, jobName string) bool {
+	if f.Branch(prefix, jobName) == "" {
+		return false
+	}
+
+	if len(f.include) > 0 {
+		included := false
+		for _, p := range f.include {
+			if p.match(jobName) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, p := range f.exclude {
+		if p.match(jobName) {
+			return false
+		}
+	}
+	return true
+}
+
+// jobNameFromURL returns the job name of a prow run URL, the path segment
+// before the build id.
+func jobNameFromURL(runURL string) string {
+	parts := strings.Split(strings.TrimSuffix(runURL, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JobKind describes how one family of prow jobs (pull, periodic, ...) is
+// searched for and how its runs are interpreted by the Analyzer.
+type JobKind interface {
+	// Name is the short identifier of the kind, e.g. "pull".
+	Name() string
+	// Title is the human readable name used in report headings.
+	Title() string
+	// JobPrefix is the start of the job names of the kind, up to the branch.
+	JobPrefix(config Config) string
+	// Group returns the key runs are grouped by in the report (PR number,
+	// cluster version, ...) or "" when the run carries none.
+	Group(config Config, run Run) string
+	// GroupLabel and GroupURL are used to render a group in the report.
+	GroupLabel(group string) string
+	GroupURL(config Config, group string) string
+	// SortGroups orders the groups of a single test failure.
+	SortGroups(groups []string)
+	// MinGroups is the number of distinct groups a failure needs to be reported.
+	MinGroups() int
+}
+
+// PullJobs are presubmit jobs, grouped by pull request.
+var PullJobs JobKind = pullJobKind{}
+
+// PeriodicJobs are periodic jobs, grouped by the cluster version in the job name.
+var PeriodicJobs JobKind = periodicJobKind{}
+
+type pullJobKind struct{}
+
+func (pullJobKind) Name() string {
+	return "pull"
+}
+
+func (pullJobKind) Title() string {
+	return "Pull"
+}
+
+func (pullJobKind) JobPrefix(config Config) string {
+	return fmt.Sprintf("pull-ci-%s-%s-", config.RepoOrg, config.RepoName)
+}
+
+func (pullJobKind) Group(config Config, run Run) string {
+	// batch runs test several PRs and are not attributed to any of them
+	if run.Prow == nil || run.Prow.PR == 0 {
+		return ""
+	}
+	return strconv.Itoa(run.Prow.PR)
+}
+
+func (pullJobKind) GroupLabel(group string) string {
+	return "#" + group
+}
+
+func (pullJobKind) GroupURL(config Config, group string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/pull/%s", config.RepoOrg, config.RepoName, group)
+}
+
+func (pullJobKind) SortGroups(groups []string) {
+	// most recent PRs first
+	sort.Slice(groups, func(i, j int) bool {
+		one, _ := strconv.Atoi(groups[i])
+		two, _ := strconv.Atoi(groups[j])
+		return one > two
+	})
+}
+
+func (pullJobKind) MinGroups() int {
+	// Skip failures that appear to be contained to a single PR
+	return 2
+}
+
+type periodicJobKind struct{}
+
+func (periodicJobKind) Name() string {
+	return "periodic"
+}
+
+func (periodicJobKind) Title() string {
+	return "Periodic"
+}
+
+func (periodicJobKind) JobPrefix(config Config) string {
+	return fmt.Sprintf("periodic-ci-%s-%s-", config.RepoOrg, config.RepoName)
+}
+
+func (k periodicJobKind) Group(config Config, run Run) string {
+	// periodic-ci-<org>-<repo>-<branch>-<version>-<test>
+	rest := strings.TrimPrefix(run.JobName, k.JobPrefix(config)+run.Branch+"-")
+	if rest == run.JobName {
+		return ""
+	}
+
+	parts := strings.Split(rest, "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+func (periodicJobKind) GroupLabel(group string) string {
+	return group
+}
+
+func (periodicJobKind) GroupURL(config Config, group string) string {
+	return ""
+}
+
+func (periodicJobKind) SortGroups(groups []string) {
+	sort.Strings(groups)
+}
+
+func (periodicJobKind) MinGroups() int {
+	return 0
+}
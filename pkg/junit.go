@@ -0,0 +1,273 @@
+package pkg
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TestStatus is the outcome of a single test case.
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "passed"
+	TestFailed  TestStatus = "failed"
+	TestSkipped TestStatus = "skipped"
+)
+
+// TestCaseResult is one <testcase> of a junit file.
+type TestCaseResult struct {
+	Suite    string
+	Name     string
+	Status   TestStatus
+	Message  string
+	Duration time.Duration
+	// File is the artifact path of the junit file, relative to the run.
+	File string
+}
+
+// JUnitStats counts the junit outcomes of one test across the fetched runs.
+type JUnitStats struct {
+	Name    string
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// FailureRate is the share of non-skipped executions that failed.
+func (s JUnitStats) FailureRate() float64 {
+	if s.Passed+s.Failed == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Passed+s.Failed)
+}
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnit parses a junit XML document whose root is either <testsuites>
+// or a single <testsuite>.
+func ParseJUnit(data []byte) ([]TestCaseResult, error) {
+	// <testsuites> and <testsuite> share the fields we read, so both roots
+	// decode into a junitSuite
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	results := []TestCaseResult{}
+	var walk func(suite junitSuite, parent string)
+	walk = func(suite junitSuite, parent string) {
+		name := suite.Name
+		if name == "" {
+			name = parent
+		}
+		for _, c := range suite.Cases {
+			results = append(results, junitResult(name, c))
+		}
+		for _, child := range suite.Suites {
+			walk(child, name)
+		}
+	}
+	walk(root, "")
+
+	return results, nil
+}
+
+func junitResult(suite string, c junitCase) TestCaseResult {
+	result := TestCaseResult{Suite: suite, Name: c.Name, Status: TestPassed}
+	if c.Name == "" {
+		result.Name = c.ClassName
+	}
+	if seconds, err := strconv.ParseFloat(c.Time, 64); err == nil {
+		result.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	switch {
+	case c.Failure != nil:
+		result.Status, result.Message = TestFailed, junitText(c.Failure)
+	case c.Error != nil:
+		result.Status, result.Message = TestFailed, junitText(c.Error)
+	case c.Skipped != nil:
+		result.Status, result.Message = TestSkipped, junitText(c.Skipped)
+	}
+	return result
+}
+
+func junitText(m *junitMessage) string {
+	if m.Message != "" {
+		return m.Message
+	}
+	return strings.TrimSpace(m.Text)
+}
+
+// isJUnitFile reports whether an artifact is a junit*.xml file.
+func isJUnitFile(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(base, "junit") && strings.HasSuffix(base, ".xml")
+}
+
+// junitMaxDepth bounds the directories below artifacts/ searched for junit
+// files. ci-operator steps write them to artifacts/<test>/<step>/artifacts/,
+// sometimes in a subdirectory.
+const junitMaxDepth = 4
+
+// skipJUnitDir tells whether an artifacts directory holds no junit files:
+// gather steps and must-gather dumps, which hold most of the artifacts.
+func skipJUnitDir(prefix string) bool {
+	base := path.Base(prefix)
+	return strings.HasPrefix(base, "gather-") || strings.HasPrefix(base, "must-gather")
+}
+
+// listJUnitFiles walks the artifacts directory of a run one level at a time,
+// so the skipped directories are never listed, and returns the junit files in
+// name order.
+func listJUnitFiles(ctx context.Context, storage *StorageClient, run ProwRun) ([]string, error) {
+	names := []string{}
+	dirs := []string{run.Path() + "/artifacts/"}
+	for depth := 0; depth <= junitMaxDepth && len(dirs) > 0; depth++ {
+		next := []string{}
+		for _, dir := range dirs {
+			list, err := storage.List(ctx, run.Bucket, dir, "/")
+			if err != nil {
+				return nil, err
+			}
+			for _, object := range list.Objects {
+				if isJUnitFile(object.Name) {
+					names = append(names, object.Name)
+				}
+			}
+			for _, prefix := range list.Prefixes {
+				if !skipJUnitDir(prefix) {
+					next = append(next, prefix)
+				}
+			}
+		}
+		dirs = next
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// fetchJUnit finds the junit files in the run's artifacts directory and
+// parses every test case in them.
+func (a *Analyzer) fetchJUnit(ctx context.Context, run *Run) []DataProblem {
+	storage := a.storageClient()
+	names, err := listJUnitFiles(ctx, storage, *run.Prow)
+	if err != nil {
+		return []DataProblem{a.problem(StageJUnit, run.URL, err)}
+	}
+
+	problems := []DataProblem{}
+	for _, name := range names {
+		file := strings.TrimPrefix(name, run.Prow.Path()+"/")
+		contents, err := downloadArtifact(ctx, storage, run.Prow.Bucket, name, a.Storage)
+		if err != nil {
+			problems = append(problems, a.problem(StageJUnit, run.URL, err))
+			continue
+		}
+
+		results, err := ParseJUnit(contents)
+		if err != nil {
+			problems = append(problems, a.problem(StageJUnit, run.URL, fmt.Errorf("parsing %s: %w", file, err)))
+			continue
+		}
+		for i := range results {
+			results[i].File = file
+		}
+		run.JUnit = append(run.JUnit, results...)
+	}
+
+	return problems
+}
+
+// junitRuns returns the runs whose junit outcomes are counted when job
+// histories are enabled: every counted run of the jobs with a history, which
+// include the failed runs found by search, and the fetched runs of the other
+// jobs.
+func junitRuns(fetched []Run, histories []JobHistory) []Run {
+	runs := []Run{}
+	withHistory := map[string]bool{}
+	for _, history := range histories {
+		withHistory[history.Job] = true
+		runs = append(runs, history.Records...)
+	}
+	for _, run := range fetched {
+		if run.Prow == nil || !withHistory[run.Prow.Job] {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// junitStats aggregates the junit outcomes of runs per test name, sorted by
+// descending failure count and then by name.
+func junitStats(runs []Run) []JUnitStats {
+	byName := map[string]*JUnitStats{}
+	for _, run := range runs {
+		for _, result := range run.JUnit {
+			stats, ok := byName[result.Name]
+			if !ok {
+				stats = &JUnitStats{Name: result.Name}
+				byName[result.Name] = stats
+			}
+			switch result.Status {
+			case TestPassed:
+				stats.Passed++
+			case TestFailed:
+				stats.Failed++
+			case TestSkipped:
+				stats.Skipped++
+			}
+		}
+	}
+
+	all := []JUnitStats{}
+	for _, stats := range byName {
+		all = append(all, *stats)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Failed != all[j].Failed {
+			return all[i].Failed > all[j].Failed
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// matchJUnit returns the stats of the junit test a failure line refers to:
+// the junit name equals the test name or ends with "/<test name>".
+func matchJUnit(stats []JUnitStats, testName string) *JUnitStats {
+	testName = strings.TrimSpace(testName)
+	if testName == "" {
+		return nil
+	}
+	for i := range stats {
+		if stats[i].Name == testName || strings.HasSuffix(stats[i].Name, "/"+testName) {
+			return &stats[i]
+		}
+	}
+	return nil
+}
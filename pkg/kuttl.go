@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxKuttlErrorLines limits the error kept for a failed step.
+const maxKuttlErrorLines = 20
+
+var (
+	// logger.go:42: 10:45:03 | 1-085_validate_sync/2-check | test step failed 2-check
+	kuttlStepEvent = regexp.MustCompile(`\| ([^/\s|]+)/((\d+)-?\S*) \| (starting test step|test step completed|test step failed)`)
+	// case.go:366: resource Deployment:ns/foo: .status.readyReplicas: value mismatch, ...
+	kuttlCaseLog = regexp.MustCompile(`^\s*\w+\.go:\d+: (.*)$`)
+	// the logger lines after a failed step, e.g. the events of the test
+	// namespace, and the go test result lines end its error
+	kuttlLogger     = regexp.MustCompile(`^\s*logger\.go:\d+: `)
+	kuttlTestResult = regexp.MustCompile(`---\s+(?:FAIL|PASS):\s+|^\s*=== `)
+	// 02-assert.yaml, 2-errors.yml
+	kuttlStepFile = regexp.MustCompile(`\b\d+-[\w.-]+\.ya?ml\b`)
+)
+
+// KuttlStepResult is the outcome of one step of a kuttl test in a run.
+type KuttlStepResult struct {
+	Test string
+	// Step is the step name, e.g. "2-check", and Index its number.
+	Step   string
+	Index  int
+	Failed bool
+	// AssertFile is the step file named in the error or, for an assertion,
+	// the conventional <index>-assert.yaml.
+	AssertFile string
+	// Error holds the error and assertion diff of a failed step.
+	Error []string
+}
+
+// ParseKuttlSteps returns the results of the kuttl test steps logged in a
+// build log, in log order.
+func ParseKuttlSteps(buildLog string) []KuttlStepResult {
+	results := []KuttlStepResult{}
+	// the case logs following a failed step are its error
+	var failed *KuttlStepResult
+	for _, line := range stripColors(strings.Split(strings.ReplaceAll(buildLog, "\r\n", "\n"), "\n")) {
+		if m := kuttlStepEvent.FindStringSubmatch(line); m != nil {
+			if failed != nil {
+				results = append(results, finishKuttlStep(*failed))
+				failed = nil
+			}
+			if m[4] == "starting test step" {
+				continue
+			}
+			index, _ := strconv.Atoi(m[3])
+			result := KuttlStepResult{Test: m[1], Step: m[2], Index: index, Failed: m[4] == "test step failed"}
+			if result.Failed {
+				failed = &result
+				continue
+			}
+			results = append(results, result)
+			continue
+		}
+		if failed == nil {
+			continue
+		}
+
+		if kuttlLogger.MatchString(line) || kuttlTestResult.MatchString(line) {
+			results = append(results, finishKuttlStep(*failed))
+			failed = nil
+			continue
+		}
+		if m := kuttlCaseLog.FindStringSubmatch(line); m != nil {
+			if !strings.HasPrefix(m[1], "failed in step") {
+				failed.Error = append(failed.Error, m[1])
+			}
+			continue
+		}
+		// assertion diffs continue on indented lines
+		if len(failed.Error) > 0 && strings.TrimSpace(line) != "" {
+			failed.Error = append(failed.Error, strings.TrimSpace(line))
+		}
+	}
+	if failed != nil {
+		results = append(results, finishKuttlStep(*failed))
+	}
+	return results
+}
+
+func finishKuttlStep(result KuttlStepResult) KuttlStepResult {
+	if len(result.Error) > maxKuttlErrorLines {
+		result.Error = append(result.Error[:maxKuttlErrorLines], fmt.Sprintf("... %d more lines", len(result.Error)-maxKuttlErrorLines))
+	}
+
+	errorText := strings.Join(result.Error, "\n")
+	if file := kuttlStepFile.FindString(errorText); file != "" {
+		result.AssertFile = file
+	} else if isKuttlAssertion(errorText) {
+		result.AssertFile = fmt.Sprintf("%02d-assert.yaml", result.Index)
+	}
+	return result
+}
+
+// isKuttlAssertion tells whether a step error is a failed assert, a diff or
+// a missing resource, rather than e.g. a failed command.
+func isKuttlAssertion(errorText string) bool {
+	for _, s := range []string{"+++ ", "value mismatch", "not found", "key is missing"} {
+		if strings.Contains(errorText, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// KuttlStepStats counts the outcomes of one kuttl test step across the
+// fetched runs.
+type KuttlStepStats struct {
+	Test       string
+	Step       string
+	Index      int
+	Passed     int
+	Failed     int
+	AssertFile string
+	// LastError is the error of the most recent failure, and FailedRuns the
+	// runs the step failed in.
+	LastError  *Excerpt
+	FailedRuns []Run
+}
+
+// FailureRate is the share of the step executions that failed.
+func (s KuttlStepStats) FailureRate() float64 {
+	if s.Passed+s.Failed == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Passed+s.Failed)
+}
+
+// kuttlStepStats aggregates the kuttl step results of runs, most failed
+// first.
+func kuttlStepStats(runs []Run) []KuttlStepStats {
+	byStep := map[string]*KuttlStepStats{}
+	for _, run := range runs {
+		for _, result := range run.KuttlSteps {
+			key := result.Test + "/" + result.Step
+			stats, ok := byStep[key]
+			if !ok {
+				stats = &KuttlStepStats{Test: result.Test, Step: result.Step, Index: result.Index}
+				byStep[key] = stats
+			}
+			if !result.Failed {
+				stats.Passed++
+				continue
+			}
+
+			stats.Failed++
+			stats.FailedRuns = append(stats.FailedRuns, run)
+			if stats.LastError == nil || runAfter(run, Run{Time: stats.LastError.Time}) {
+				stats.LastError = &Excerpt{URL: run.BuildLogURL, Time: run.Time, Lines: result.Error}
+				stats.AssertFile = result.AssertFile
+			}
+		}
+	}
+
+	all := []KuttlStepStats{}
+	for _, stats := range byStep {
+		all = append(all, *stats)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Failed != all[j].Failed {
+			return all[i].Failed > all[j].Failed
+		}
+		if all[i].Test != all[j].Test {
+			return all[i].Test < all[j].Test
+		}
+		return all[i].Index < all[j].Index
+	})
+	return all
+}
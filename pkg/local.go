@@ -0,0 +1,412 @@
+package pkg
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// localBucket is used for runs whose directory layout does not name a bucket.
+const localBucket = "local"
+
+// localObject is a file of a LocalSource, on disk.
+type localObject struct {
+	path string
+}
+
+// LocalSource serves build logs and artifacts from a directory or .tar.gz
+// instead of search.ci and GCS. Every directory containing a build-log.txt is
+// a run. It is identified by the status.url of its prowjob.json, or else by a
+// prow layout in its path, e.g.
+//
+//	<dir>/logs/<job>/<build id>/build-log.txt
+//	<dir>/<bucket>/pr-logs/pull/<org>_<repo>/<pr>/<job>/<build id>/build-log.txt
+type LocalSource struct {
+	runs []ProwRun
+	// objects maps "<bucket>/<object path>" to the local file.
+	objects map[string]localObject
+	// Problems lists the runs that could not be identified.
+	Problems []DataProblem
+	// tempDir holds the files extracted from an archive.
+	tempDir string
+}
+
+// OpenLocal reads the file tree of a directory or .tar.gz archive. The runs
+// of an archive are extracted to a temporary directory, removed by Close.
+func OpenLocal(name string) (*LocalSource, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, tempDir := name, ""
+	if !info.IsDir() {
+		if !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+			return nil, fmt.Errorf("%s is neither a directory nor a .tar.gz archive", name)
+		}
+		tempDir, err = os.MkdirTemp("", "flake-dashboard-local-")
+		if err != nil {
+			return nil, err
+		}
+		if err := extractTarGz(name, tempDir); err != nil {
+			os.RemoveAll(tempDir)
+			return nil, err
+		}
+		dir = tempDir
+	}
+
+	files := map[string]localObject{}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = localObject{path: p}
+		return nil
+	})
+	if err != nil {
+		if tempDir != "" {
+			os.RemoveAll(tempDir)
+		}
+		return nil, err
+	}
+
+	source := newLocalSource(files)
+	source.tempDir = tempDir
+	return source, nil
+}
+
+// Close removes the files extracted from an archive.
+func (s *LocalSource) Close() error {
+	if s.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.tempDir)
+}
+
+// extractTarGz extracts the runs of a .tar.gz archive to dir, without
+// holding any file in memory. A first pass finds the run directories, those
+// holding a build-log.txt, so the second only writes the files under them and
+// the pr-logs/directory links, not e.g. a must-gather next to them.
+func extractTarGz(name, dir string) error {
+	runDirs := map[string]bool{}
+	err := walkTarGz(name, func(file string, r io.Reader) error {
+		if path.Base(file) == "build-log.txt" {
+			runDirs[path.Dir(file)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return walkTarGz(name, func(file string, r io.Reader) error {
+		if !inLocalRun(file, runDirs) && !strings.Contains("/"+file, "/pr-logs/directory/") {
+			return nil
+		}
+		target := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		return f.Close()
+	})
+}
+
+// walkTarGz calls fn with the name and contents of every regular file of a
+// .tar.gz archive. Names escaping the archive root are skipped.
+func walkTarGz(name string, fn func(file string, r io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		file := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if header.Typeflag != tar.TypeReg || !fs.ValidPath(file) {
+			continue
+		}
+		if err := fn(file, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// inLocalRun tells whether a file is below one of runDirs.
+func inLocalRun(file string, runDirs map[string]bool) bool {
+	for dir := path.Dir(file); ; dir = path.Dir(dir) {
+		if runDirs[dir] {
+			return true
+		}
+		if dir == "." || dir == "/" {
+			return false
+		}
+	}
+}
+
+func newLocalSource(files map[string]localObject) *LocalSource {
+	source := &LocalSource{objects: map[string]localObject{}}
+
+	runDirs := []string{}
+	for name := range files {
+		if path.Base(name) == "build-log.txt" {
+			runDirs = append(runDirs, path.Dir(name))
+		}
+	}
+	sort.Strings(runDirs)
+
+	for _, dir := range runDirs {
+		run, err := identifyLocalRun(dir, files)
+		if err != nil {
+			source.Problems = append(source.Problems, DataProblem{Stage: StageURL, RunURL: dir, Err: err.Error()})
+			continue
+		}
+		source.runs = append(source.runs, *run)
+
+		// a run stored in its bucket layout makes the whole bucket available,
+		// e.g. the pr-logs/directory links used for job histories
+		root, dst := dir+"/", run.Bucket+"/"+run.Path()+"/"
+		if dir == run.Path() {
+			root, dst = "", run.Bucket+"/"
+		} else if prefix, ok := strings.CutSuffix(dir, "/"+run.Path()); ok {
+			root, dst = prefix+"/", run.Bucket+"/"
+		}
+		for name, object := range files {
+			if rel, ok := strings.CutPrefix(name, root); ok {
+				source.objects[dst+rel] = object
+			}
+		}
+	}
+
+	return source
+}
+
+// identifyLocalRun finds the prow run stored in dir.
+func identifyLocalRun(dir string, files map[string]localObject) (*ProwRun, error) {
+	if object, ok := files[path.Join(dir, "prowjob.json")]; ok {
+		var job prowJob
+		if data, err := object.read(); err == nil && json.Unmarshal(data, &job) == nil && job.Status.URL != "" {
+			if run, err := ParseProwURL(job.Status.URL); err == nil {
+				return run, nil
+			}
+		}
+	}
+
+	if dir == "." {
+		return nil, fmt.Errorf("no prow job layout in path %q", dir)
+	}
+	segments := strings.Split(dir, "/")
+	for i, segment := range segments {
+		if segment != "logs" && segment != "pr-logs" {
+			continue
+		}
+		bucket := localBucket
+		if i > 0 {
+			bucket = segments[i-1]
+		}
+		if run, err := ParseProwURL("gs://" + bucket + "/" + strings.Join(segments[i:], "/")); err == nil {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no prow job layout in path %q", dir)
+}
+
+func (o localObject) read() ([]byte, error) {
+	return os.ReadFile(o.path)
+}
+
+// Search implements SearchClient over the local build logs. Unlike search.ci
+// it ignores MaxAge, so old logs can be re-analyzed. Other search types have
+// no local index and find nothing.
+func (s *LocalSource) Search(ctx context.Context, query SearchQuery) (Result, error) {
+	if query.Type != "" && query.Type != SearchTypeBuildLog {
+		return Result{}, nil
+	}
+
+	searches := []*regexp.Regexp{}
+	for _, search := range query.Search {
+		re, err := regexp.Compile(search)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search %q: %w", search, err)
+		}
+		searches = append(searches, re)
+	}
+	var name *regexp.Regexp
+	if query.Name != "" {
+		var err error
+		if name, err = regexp.Compile(query.Name); err != nil {
+			return nil, fmt.Errorf("invalid job name %q: %w", query.Name, err)
+		}
+	}
+
+	result := Result{}
+	for _, run := range s.runs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if name != nil && !name.MatchString(run.Job) {
+			continue
+		}
+
+		data, err := s.objects[run.Bucket+"/"+run.Path()+"/build-log.txt"].read()
+		if err != nil {
+			return nil, err
+		}
+		if query.MaxBytes > 0 && int64(len(data)) > query.MaxBytes {
+			data = data[len(data)-int(query.MaxBytes):]
+		}
+
+		lines := strings.Split(string(data), "\n")
+		for i, search := range searches {
+			matches := searchLines(lines, search, query.Context, query.MaxMatches)
+			if len(matches) == 0 {
+				continue
+			}
+			if result[run.ViewURL()] == nil {
+				result[run.ViewURL()] = map[string][]Match{}
+			}
+			result[run.ViewURL()][query.Search[i]] = matches
+		}
+	}
+	return result, nil
+}
+
+// searchLines returns every line matching search with context lines around
+// it, like search.ci does, up to maxMatches when it is set.
+func searchLines(lines []string, search *regexp.Regexp, context, maxMatches int) []Match {
+	matches := []Match{}
+	for i, line := range lines {
+		if !search.MatchString(line) {
+			continue
+		}
+		if maxMatches > 0 && len(matches) == maxMatches {
+			break
+		}
+		start, end := i-context, i+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		matches = append(matches, Match{FileType: "build-log.txt", Context: lines[start:end]})
+	}
+	return matches
+}
+
+// Client returns an HTTP client that serves artifact downloads and GCS
+// object listings of any host from the local files, so report links keep
+// pointing at the real storage.
+func (s *LocalSource) Client() *http.Client {
+	return &http.Client{Transport: localTransport{s}}
+}
+
+type localTransport struct {
+	source *LocalSource
+}
+
+func (t localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rest, ok := strings.CutPrefix(req.URL.Path, "/storage/v1/b/"); ok {
+		bucket, _ := strings.CutSuffix(rest, "/o")
+		return t.list(req, bucket)
+	}
+
+	object, ok := t.source.objects[strings.TrimPrefix(req.URL.Path, "/")]
+	if !ok {
+		return localResponse(req, http.StatusNotFound, nil), nil
+	}
+	data, err := object.read()
+	if err != nil {
+		return nil, err
+	}
+	return localResponse(req, http.StatusOK, data), nil
+}
+
+// list answers a GCS JSON API objects.list request in a single page.
+func (t localTransport) list(req *http.Request, bucket string) (*http.Response, error) {
+	prefix := req.URL.Query().Get("prefix")
+	delimiter := req.URL.Query().Get("delimiter")
+
+	page := gcsObjectList{}
+	seen := map[string]bool{}
+	names := []string{}
+	for name := range t.source.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		object, ok := strings.CutPrefix(name, bucket+"/")
+		if !ok || !strings.HasPrefix(object, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(object[len(prefix):], delimiter); i >= 0 {
+				dir := object[:len(prefix)+i+len(delimiter)]
+				if !seen[dir] {
+					seen[dir] = true
+					page.Prefixes = append(page.Prefixes, dir)
+				}
+				continue
+			}
+		}
+		page.Items = append(page.Items, gcsObject{Name: object})
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	return localResponse(req, http.StatusOK, data), nil
+}
+
+func localResponse(req *http.Request, status int, data []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
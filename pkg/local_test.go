@@ -0,0 +1,234 @@
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLocalFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIdentifyLocalRun(t *testing.T) {
+	const job = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	pull := ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: 101, Job: job, BuildID: "1700000000000000001"}
+
+	dir := t.TempDir()
+	writeLocalFiles(t, dir, map[string]string{
+		"download/prowjob.json":                `{"status": {"url": "` + pull.ViewURL() + `"}}`,
+		"broken/logs/job/123/prowjob.json":     `{"status": `,
+		"nourl/logs/job/124/prowjob.json":      `{"status": {"state": "failure"}}`,
+		"badurl/logs/job/125/prowjob.json":     `{"status": {"url": "https://example.com/not/prow"}}`,
+		"elsewhere/logs/job/126/build-log.txt": "",
+	})
+	files := map[string]localObject{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files[filepath.ToSlash(rel)] = localObject{path: p}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		want *ProwRun
+	}{
+		// prowjob.json names the run wherever it is stored
+		{"download", &pull},
+		// else the path has a prow layout, below its bucket if any
+		{"broken/logs/job/123", &ProwRun{Bucket: "broken", Job: "job", BuildID: "123"}},
+		{"nourl/logs/job/124", &ProwRun{Bucket: "nourl", Job: "job", BuildID: "124"}},
+		{"badurl/logs/job/125", &ProwRun{Bucket: "badurl", Job: "job", BuildID: "125"}},
+		{"logs/job/127", &ProwRun{Bucket: localBucket, Job: "job", BuildID: "127"}},
+		{"runs/origin-ci-test/logs/job/128", &ProwRun{Bucket: "origin-ci-test", Job: "job", BuildID: "128"}},
+		{"test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001", &pull},
+		{"test-platform-results/pr-logs/pull/batch/" + job + "/1700000000000000002", &ProwRun{Bucket: "test-platform-results", Batch: true, Job: job, BuildID: "1700000000000000002"}},
+
+		{".", nil},
+		{"some/download", nil},
+		{"logs/job/latest", nil},
+		{"pr-logs/pull/redhat-developer_gitops-operator/abc/" + job + "/1", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, err := identifyLocalRun(tt.dir, files)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("identifyLocalRun(%q) = %+v, want an error", tt.dir, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("identifyLocalRun(%q): %v", tt.dir, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("identifyLocalRun(%q) = %+v, want %+v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTarGz archives the files of dir the way tar czf archive.tar.gz . does.
+func writeTarGz(t *testing.T, dir, name string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = "./" + filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateLocalReport(t *testing.T) {
+	const job = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	started := time.Now().Add(-time.Hour).Unix()
+	buildLog := "--- FAIL: kuttl/harness/1-085_validate_sync (120.37s)\n--- FAIL: kuttl/harness/1-031_validate_toolchain (10.00s)\nFAIL\n"
+
+	dir := t.TempDir()
+	files := map[string]string{
+		// runs in their bucket layout
+		"test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/build-log.txt": buildLog,
+		"test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/started.json":  `{"timestamp": ` + strconv.FormatInt(started, 10) + `}`,
+		"test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/102/" + job + "/1700000000000000002/build-log.txt": buildLog,
+		// a run downloaded on its own
+		"download/build-log.txt": "--- FAIL: kuttl/harness/1-085_validate_sync (99.00s)\n",
+		"download/prowjob.json":  `{"status": {"url": "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/103/` + job + `/1700000000000000003"}}`,
+		// not a run
+		"unknown/build-log.txt":          buildLog,
+		"must-gather/namespaces/big.log": strings.Repeat("not a build log\n", 1000),
+	}
+	writeLocalFiles(t, dir, files)
+	archive := filepath.Join(t.TempDir(), "runs.tar.gz")
+	writeTarGz(t, dir, archive)
+
+	config := Config{
+		RepoOrg:   "redhat-developer",
+		RepoName:  "gitops-operator",
+		SearchStr: "--- FAIL: kuttl/harness/",
+		Regex:     `---\s+FAIL:\s+kuttl/harness/`,
+	}
+
+	fromDir, err := GenerateLocalReport(context.Background(), config, dir, PullJobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromArchive, err := GenerateLocalReport(context.Background(), config, archive, PullJobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section := fromDir.Targets[0].Sections[0]
+	fails := map[string]int{}
+	for _, test := range section.Tests {
+		fails[test.Name] = test.Fails
+	}
+	if want := map[string]int{"1-085_validate_sync": 3, "1-031_validate_toolchain": 2}; !reflect.DeepEqual(fails, want) {
+		t.Errorf("GenerateLocalReport(dir) fails = %v, want %v", fails, want)
+	}
+	if len(fromDir.Problems) != 1 || fromDir.Problems[0].RunURL != "unknown" {
+		t.Errorf("GenerateLocalReport(dir) problems = %+v, want one for the unknown run", fromDir.Problems)
+	}
+
+	// the archive gives the same report
+	fromArchive.GeneratedAt = fromDir.GeneratedAt
+	if !reflect.DeepEqual(fromArchive, fromDir) {
+		t.Errorf("GenerateLocalReport(archive) =\n%+v\nwant\n%+v", fromArchive, fromDir)
+	}
+}
+
+func TestOpenLocalArchiveKeepsRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeLocalFiles(t, dir, map[string]string{
+		"origin-ci-test/logs/job/1/build-log.txt":                     "FAIL\n",
+		"origin-ci-test/logs/job/1/artifacts/junit_e2e.xml":           "<testsuite/>",
+		"origin-ci-test/pr-logs/directory/job/1.txt":                  "gs://origin-ci-test/pr-logs/pull/org_repo/1/job/1",
+		"must-gather/namespaces/openshift-gitops/pods/argocd/big.log": "not a build log\n",
+	})
+	archive := filepath.Join(t.TempDir(), "runs.tgz")
+	writeTarGz(t, dir, archive)
+
+	source, err := OpenLocal(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for name := range source.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := []string{
+		"origin-ci-test/logs/job/1/artifacts/junit_e2e.xml",
+		"origin-ci-test/logs/job/1/build-log.txt",
+		"origin-ci-test/pr-logs/directory/job/1.txt",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("OpenLocal() objects = %q, want %q", names, want)
+	}
+
+	tempDir := source.tempDir
+	if err := source.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Errorf("Close() left %s behind", tempDir)
+	}
+}
@@ -0,0 +1,437 @@
+package pkg
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarkdownRenderer renders a Report as the markdown tables published in
+// output/flake-stats.md.
+type MarkdownRenderer struct{}
+
+const markdownTimeLayout = "2006-01-02 15:04 UTC"
+
+// summarySize is the number of worst flakes listed in the org-wide summary.
+const summarySize = 20
+
+// Render ...
+func (MarkdownRenderer) Render(w io.Writer, report *Report) error {
+	var sb strings.Builder
+
+	// a single target keeps the historical layout, without target headings
+	multiTarget := len(report.Targets) > 1
+	if multiTarget {
+		renderMarkdownSummary(&sb, report)
+	}
+
+	for _, target := range report.Targets {
+		if multiTarget {
+			fmt.Fprintf(&sb, "\n# %s\n", target.Target)
+		}
+		for _, section := range target.Sections {
+			renderMarkdownSection(&sb, report, section)
+			renderMarkdownSignatures(&sb, section)
+			renderMarkdownJUnit(&sb, section)
+			renderMarkdownSteps(&sb, section)
+			renderMarkdownInfra(&sb, section)
+			renderMarkdownJobs(&sb, section)
+		}
+	}
+
+	if problems := report.AllProblems(); len(problems) > 0 {
+		sb.WriteString("\n### Data problems\n")
+		fmt.Fprintf(&sb, "*This report is %s: the following data could not be fetched or parsed.*\n\n", report.Status())
+		sb.WriteString("| Repository | Job Kind | Stage | Run | Error |\n")
+		sb.WriteString("|---|---|---|---|---|\n")
+		for _, p := range problems {
+			run := ""
+			if p.RunURL != "" {
+				run = fmt.Sprintf("[link](%s)", p.RunURL)
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", p.Target, p.Kind, p.Stage, run, markdownEscape(p.Err))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func renderMarkdownSection(sb *strings.Builder, report *Report, section Section) {
+	if len(section.Tests) == 0 {
+		fmt.Fprintf(sb, "\n### *No Test failures found for last %s of __%s__ test runs*\n", formatWindow(section.Window), section.Title)
+		return
+	}
+
+	fmt.Fprintf(sb, "## FLAKY TESTS: Failed test scenarios in past %s\n", formatWindow(section.Window))
+
+	// a target with a single category keeps the historical layout
+	if len(section.Categories) <= 1 {
+		renderMarkdownTests(sb, report, section, section.Tests)
+		return
+	}
+	for _, category := range section.Categories {
+		tests := []TestReport{}
+		for _, test := range section.Tests {
+			if test.Category == category {
+				tests = append(tests, test)
+			}
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		fmt.Fprintf(sb, "\n### %s\n", category)
+		renderMarkdownTests(sb, report, section, tests)
+	}
+}
+
+func renderMarkdownTests(sb *strings.Builder, report *Report, section Section, tests []TestReport) {
+	sb.WriteString("| Failure Score<sup>*</sup> | Failures | Failure Rate | Test Name | Branches | Breakdown | Last Seen | PR List and Logs \n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, test := range tests {
+		fmt.Fprintf(sb, "| %d | %s | %s | %s | %s | %s | %s | %s\n", test.Score, markdownFails(section, test), markdownRate(test.FailedRuns, test.TotalRuns), markdownTestName(test)+markdownFailure(test)+markdownExcerpt(test.Excerpt), strings.Join(test.Branches, ", "), markdownBreakdown(test.Breakdown), markdownLastSeen(report, test), markdownGroups(test.Groups))
+	}
+}
+
+// renderMarkdownSignatures lists the signatures shared by several tests, the
+// others repeat the test table.
+func renderMarkdownSignatures(sb *strings.Builder, section Section) {
+	shared := []FailureSignature{}
+	for _, signature := range section.Signatures {
+		if len(signature.Tests) > 1 {
+			shared = append(shared, signature)
+		}
+	}
+	if len(shared) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n### Shared failure signatures of __%s__ tests\n", section.Title)
+	sb.WriteString("| Signature | Runs | Tests | Example Logs \n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, signature := range shared {
+		tests := []string{}
+		for _, test := range signature.Tests {
+			name := markdownEscape(test.Name)
+			if len(section.Categories) > 1 {
+				name += fmt.Sprintf(" (%s)", test.Category)
+			}
+			tests = append(tests, name)
+		}
+		examples := []string{}
+		for i, run := range signature.Examples {
+			logURL := run.BuildLogURL
+			if logURL == "" {
+				logURL = run.URL
+			}
+			examples = append(examples, fmt.Sprintf("[%d](%s)", i+1, logURL))
+		}
+		fmt.Fprintf(sb, "| %s | %d | %s | %s\n", markdownEscape(signature.Signature), signature.RunCount(), strings.Join(tests, "<br>"), strings.Join(examples, ", "))
+	}
+}
+
+func renderMarkdownJUnit(sb *strings.Builder, section Section) {
+	failing := []JUnitStats{}
+	for _, stats := range section.JUnit {
+		if stats.Failed > 0 {
+			failing = append(failing, stats)
+		}
+	}
+	if len(failing) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n### JUnit outcomes of failing __%s__ tests\n", section.Title)
+	if section.JUnitAllRuns {
+		sb.WriteString("| Test Name | Failed | Passed | Skipped | Failure Rate \n")
+	} else {
+		// only the runs found by search, which all failed, are fetched
+		sb.WriteString("Counted over the fetched failed runs only, not every run of the jobs.\n\n")
+		sb.WriteString("| Test Name | Failed | Passed | Skipped | Failure Rate in Failed Runs \n")
+	}
+	sb.WriteString("|---|---|---|---|---|\n")
+	for _, stats := range failing {
+		fmt.Fprintf(sb, "| %s | %d | %d | %d | %.0f%%\n", stats.Name, stats.Failed, stats.Passed, stats.Skipped, 100*stats.FailureRate())
+	}
+}
+
+func renderMarkdownSteps(sb *strings.Builder, section Section) {
+	failing := []KuttlStepStats{}
+	for _, stats := range section.Steps {
+		if stats.Failed > 0 {
+			failing = append(failing, stats)
+		}
+	}
+	if len(failing) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n### Failing kuttl steps of __%s__ tests\n", section.Title)
+	sb.WriteString("| Test Name | Step | Failed | Passed | Failure Rate | Assert File | Last Error | Logs \n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, stats := range failing {
+		logs := []string{}
+		for i, run := range stats.FailedRuns {
+			logs = append(logs, fmt.Sprintf("[%d](%s)", i+1, run.BuildLogURL))
+		}
+		fmt.Fprintf(sb, "| %s | %s | %d | %d | %.0f%% | %s | %s | %s\n", stats.Test, stats.Step, stats.Failed, stats.Passed, 100*stats.FailureRate(), stats.AssertFile, markdownExcerpt(stats.LastError), strings.Join(logs, ", "))
+	}
+}
+
+func renderMarkdownInfra(sb *strings.Builder, section Section) {
+	if len(section.Infra) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n### Infrastructure failures of __%s__ jobs\n", section.Title)
+	sb.WriteString("| Job | Infra Runs | Reasons \n")
+	sb.WriteString("|---|---|---|\n")
+	for _, summary := range section.Infra {
+		reasons := []string{}
+		for _, reason := range summary.Reasons {
+			reasons = append(reasons, fmt.Sprintf("%s (%d)", reason.Value, reason.Fails))
+		}
+		fmt.Fprintf(sb, "| %s | %s | %s\n", summary.Job, markdownRate(summary.Infra, summary.Runs), strings.Join(reasons, ", "))
+	}
+}
+
+func renderMarkdownJobs(sb *strings.Builder, section Section) {
+	if len(section.Jobs) == 0 {
+		return
+	}
+
+	fmt.Fprintf(sb, "\n### __%s__ job failure rates in past %s\n", section.Title, formatWindow(section.Window))
+	sb.WriteString("| Job | Runs | Failure Rate \n")
+	sb.WriteString("|---|---|---|\n")
+	for _, job := range section.Jobs {
+		if job.Runs == 0 {
+			continue
+		}
+		runs := strconv.Itoa(job.Runs)
+		if job.Truncated {
+			runs += "+"
+		}
+		fmt.Fprintf(sb, "| %s | %s | %s\n", job.Job, runs, markdownRate(job.Failed, job.Runs))
+	}
+}
+
+// markdownRate renders failed/total as a percentage, or "" without runs.
+func markdownRate(failed, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d (%.0f%%)", failed, total, 100*float64(failed)/float64(total))
+}
+
+// markdownTestName marks the test name with its retest verdict and the bugs
+// mentioning it, when known.
+func markdownTestName(test TestReport) string {
+	name := test.Name
+	switch test.Retest {
+	case RetestConfirmedFlake:
+		name += fmt.Sprintf(" <sup>**%s**</sup>", test.Retest)
+	case RetestConsistent:
+		name += fmt.Sprintf(" <sup>%s</sup>", test.Retest)
+	}
+
+	if len(test.TrackedBy) > 0 {
+		links := []string{}
+		for _, bug := range test.TrackedBy {
+			title := bug.Title
+			if bug.Status != "" {
+				title = strings.TrimSpace(fmt.Sprintf("%s (%s)", title, bug.Status))
+			}
+			if title != "" {
+				links = append(links, fmt.Sprintf("[%s](%s %q)", bug.ID, bug.URL, title))
+			} else {
+				links = append(links, fmt.Sprintf("[%s](%s)", bug.ID, bug.URL))
+			}
+		}
+		name += " <sup>possibly tracked by " + strings.Join(links, ", ") + "</sup>"
+	}
+	return name
+}
+
+// maxMessageLength truncates failure messages in the test table.
+const maxMessageLength = 200
+
+// markdownFailure renders the failure message and location found by an
+// extractor below the test name.
+func markdownFailure(test TestReport) string {
+	message := test.Message
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength] + "..."
+	}
+	if test.Location != "" {
+		message = strings.TrimSpace(fmt.Sprintf("%s (%s)", message, test.Location))
+	}
+	if message == "" {
+		return ""
+	}
+	return "<br><sub>" + markdownEscape(message) + "</sub>"
+}
+
+// markdownExcerpt renders a build log excerpt as a collapsed block, kept on
+// one line to fit in a table cell.
+func markdownExcerpt(excerpt *Excerpt) string {
+	if excerpt == nil || len(excerpt.Lines) == 0 {
+		return ""
+	}
+	lines := []string{}
+	for _, line := range excerpt.Lines {
+		line = html.EscapeString(strings.TrimRight(line, " \t"))
+		lines = append(lines, strings.ReplaceAll(line, "|", "&#124;"))
+	}
+	summary := "log excerpt"
+	if excerpt.Time != nil {
+		summary += " of " + excerpt.Time.UTC().Format(markdownTimeLayout)
+	}
+	link := ""
+	if excerpt.URL != "" {
+		link = fmt.Sprintf(`<a href="%s">build log</a>`, html.EscapeString(excerpt.URL))
+	}
+	return "<details><summary>" + summary + "</summary>" + link + "<pre>" + strings.Join(lines, "<br>") + "</pre></details>"
+}
+
+// markdownBreakdown lists the failing runs per version, platform and variant,
+// one dimension per line.
+func markdownBreakdown(b Breakdown) string {
+	lines := []string{}
+	for _, counts := range [][]DimensionCount{b.Versions, b.Platforms, b.Variants} {
+		values := []string{}
+		for _, c := range counts {
+			values = append(values, fmt.Sprintf("%s (%d)", c.Value, c.Fails))
+		}
+		if len(values) > 0 {
+			lines = append(lines, strings.Join(values, ", "))
+		}
+	}
+	return strings.Join(lines, "<br>")
+}
+
+func markdownFails(section Section, test TestReport) string {
+	if test.JUnit == nil {
+		return strconv.Itoa(test.Fails)
+	}
+	if section.JUnitAllRuns {
+		return fmt.Sprintf("%d<sup>junit %d/%d</sup>", test.Fails, test.JUnit.Failed, test.JUnit.Failed+test.JUnit.Passed)
+	}
+	return fmt.Sprintf("%d<sup>junit %d/%d in failed runs</sup>", test.Fails, test.JUnit.Failed, test.JUnit.Failed+test.JUnit.Passed)
+}
+
+func renderMarkdownSummary(sb *strings.Builder, report *Report) {
+	summary := report.Summary()
+	if len(summary) > summarySize {
+		summary = summary[:summarySize]
+	}
+
+	sb.WriteString("# FLAKY TESTS: Worst failures across all repositories\n")
+	if len(summary) == 0 {
+		sb.WriteString("\n*No Test failures found*\n")
+		return
+	}
+
+	sb.WriteString("| Failure Score<sup>*</sup> | Failures | Repository | Job Kind | Test Name | Last Seen \n")
+	sb.WriteString("|---|---|---|---|---|---|\n")
+	for _, entry := range summary {
+		fmt.Fprintf(sb, "| %d | %d | %s | %s | %s | %s\n", entry.Test.Score, entry.Test.Fails, entry.Target, entry.Kind, markdownTestName(entry.Test), markdownLastSeen(report, entry.Test))
+	}
+}
+
+func markdownLastSeen(report *Report, test TestReport) string {
+	if test.LastSeen == nil {
+		return ""
+	}
+	days := report.GeneratedAt.Sub(*test.LastSeen).Hours() / 24
+	return fmt.Sprintf("%d days ago (%s)", int(days), test.LastSeen.UTC().Format(markdownTimeLayout))
+}
+
+// markdownRunTitle is the hover text of a run's log link.
+func markdownRunTitle(run Run) string {
+	parts := []string{}
+	if run.Time != nil {
+		parts = append(parts, run.Time.UTC().Format(markdownTimeLayout))
+	}
+	if run.Result != "" {
+		parts = append(parts, run.Result)
+	}
+	if run.Duration > 0 {
+		parts = append(parts, run.Duration.Round(time.Minute).String())
+	}
+	if run.Refs != nil && len(run.Refs.Pulls) > 0 && run.Refs.Pulls[0].Author != "" {
+		parts = append(parts, "by "+run.Refs.Pulls[0].Author)
+	}
+	if run.Class == RunInfra {
+		parts = append(parts, "infra: "+run.ClassRule)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownCommit links the commit a run tested: the PR head for presubmits,
+// the base for everything else.
+func markdownCommit(run Run) string {
+	if run.Refs == nil {
+		return ""
+	}
+	sha := run.Refs.HeadSHA()
+	if sha == "" {
+		sha = run.Refs.BaseSHA
+	}
+	if sha == "" || run.Refs.Org == "" || run.Refs.Repo == "" {
+		return ""
+	}
+	short := sha
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return fmt.Sprintf("[`%s`](https://github.com/%s/%s/commit/%s)", short, run.Refs.Org, run.Refs.Repo, sha)
+}
+
+func markdownGroups(groups []RunGroup) string {
+	groupListString := fmt.Sprintf("%d: ", len(groups))
+	for _, group := range groups {
+		if group.URL != "" {
+			groupListString += fmt.Sprintf("[%s](%s)", group.Label, group.URL)
+		} else {
+			groupListString += fmt.Sprintf("[%s]", group.Label)
+		}
+
+		if len(group.Runs) > 0 {
+
+			groupListString += "<sup>"
+
+			for index, run := range group.Runs {
+				logURL := run.BuildLogURL
+				if logURL == "" {
+					logURL = run.URL
+				}
+				link := logURL
+				if title := markdownRunTitle(run); title != "" {
+					link += fmt.Sprintf(" %q", title)
+				}
+				groupListString += "[" + strconv.FormatInt(int64(index+1), 10) + "](" + link + ")"
+				if commit := markdownCommit(run); commit != "" {
+					groupListString += "@" + commit
+				}
+
+				if index+1 != len(group.Runs) {
+					groupListString += ", "
+				}
+			}
+
+			groupListString += "</sup>"
+		}
+
+		groupListString += " "
+	}
+	return groupListString
+}
+
+// markdownEscape keeps free text from breaking a table row.
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
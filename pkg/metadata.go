@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Refs are the git refs a run tested.
+type Refs struct {
+	Org     string
+	Repo    string
+	BaseRef string
+	BaseSHA string
+	Pulls   []Pull
+}
+
+// Pull is a pull request tested by a run.
+type Pull struct {
+	Number int
+	Author string
+	SHA    string
+}
+
+// HeadSHA returns the head commit of the run's first pull request, or "".
+func (r *Refs) HeadSHA() string {
+	if r == nil || len(r.Pulls) == 0 {
+		return ""
+	}
+	return r.Pulls[0].SHA
+}
+
+// prowStarted is the subset of started.json used by the dashboard.
+type prowStarted struct {
+	Timestamp int64 `json:"timestamp"`
+}
+
+// prowFinished is the subset of finished.json used by the dashboard.
+type prowFinished struct {
+	Timestamp int64  `json:"timestamp"`
+	Passed    *bool  `json:"passed"`
+	Result    string `json:"result"`
+}
+
+// prowJob is the subset of prowjob.json used by the dashboard.
+type prowJob struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		Refs *struct {
+			Org     string `json:"org"`
+			Repo    string `json:"repo"`
+			BaseRef string `json:"base_ref"`
+			BaseSHA string `json:"base_sha"`
+			Pulls   []struct {
+				Number int    `json:"number"`
+				Author string `json:"author"`
+				SHA    string `json:"sha"`
+			} `json:"pulls"`
+		} `json:"refs"`
+	} `json:"spec"`
+	Status struct {
+		StartTime      *time.Time `json:"startTime"`
+		CompletionTime *time.Time `json:"completionTime"`
+		State          string     `json:"state"`
+		URL            string     `json:"url"`
+	} `json:"status"`
+}
+
+// fetchMetadata fills the start and finish time, result and refs of the run
+// from its started.json, finished.json and prowjob.json. Missing files are
+// not problems: a run may still be in progress or predate prowjob.json
+// uploads, and the start time falls back to the build log.
+func (a *Analyzer) fetchMetadata(ctx context.Context, run *Run) []DataProblem {
+	problems := []DataProblem{}
+
+	var started prowStarted
+	if err := a.fetchJSON(ctx, run, "started.json", &started); err != nil {
+		if !errors.Is(err, errArtifactNotFound) {
+			problems = append(problems, a.problem(StageMetadata, run.URL, err))
+		}
+	} else if started.Timestamp > 0 {
+		t := time.Unix(started.Timestamp, 0).UTC()
+		run.Started = &t
+	}
+
+	var finished prowFinished
+	if err := a.fetchJSON(ctx, run, "finished.json", &finished); err != nil {
+		if !errors.Is(err, errArtifactNotFound) {
+			problems = append(problems, a.problem(StageMetadata, run.URL, err))
+		}
+	} else {
+		if finished.Timestamp > 0 {
+			t := time.Unix(finished.Timestamp, 0).UTC()
+			run.Finished = &t
+		}
+		run.Result = finished.Result
+		if run.Result == "" && finished.Passed != nil {
+			run.Result = "FAILURE"
+			if *finished.Passed {
+				run.Result = "SUCCESS"
+			}
+		}
+	}
+
+	var job prowJob
+	if err := a.fetchJSON(ctx, run, "prowjob.json", &job); err != nil {
+		if !errors.Is(err, errArtifactNotFound) {
+			problems = append(problems, a.problem(StageMetadata, run.URL, err))
+		}
+	} else {
+		run.Labels = job.Metadata.Labels
+		if refs := job.Spec.Refs; refs != nil {
+			run.Refs = &Refs{Org: refs.Org, Repo: refs.Repo, BaseRef: refs.BaseRef, BaseSHA: refs.BaseSHA}
+			for _, pull := range refs.Pulls {
+				run.Refs.Pulls = append(run.Refs.Pulls, Pull{Number: pull.Number, Author: pull.Author, SHA: pull.SHA})
+			}
+		}
+		if run.Started == nil && job.Status.StartTime != nil {
+			t := job.Status.StartTime.UTC()
+			run.Started = &t
+		}
+		if run.Finished == nil && job.Status.CompletionTime != nil {
+			t := job.Status.CompletionTime.UTC()
+			run.Finished = &t
+		}
+		if run.Result == "" && job.Status.State != "" {
+			run.Result = strings.ToUpper(job.Status.State)
+		}
+	}
+
+	if run.Started != nil && run.Finished != nil {
+		run.Duration = run.Finished.Sub(*run.Started)
+	}
+
+	return problems
+}
+
+func (a *Analyzer) fetchJSON(ctx context.Context, run *Run, name string, v interface{}) error {
+	contents, err := downloadArtifact(ctx, a.storageClient(), run.Prow.Bucket, run.Prow.Path()+"/"+name, a.Storage)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// lineCleanup is removed from every failure line before it is shown: colors,
+// the kuttl harness prefix and the go test duration.
+var lineCleanup = []*regexp.Regexp{
+	regexp.MustCompile(ansiTime),
+	regexp.MustCompile(ansiPrefix),
+	regexp.MustCompile(ansiCommon),
+}
+
+// cleanLine strips lineCleanup and surrounding spaces from a failure line.
+func cleanLine(line string) string {
+	for _, re := range lineCleanup {
+		line = StripAnsi(line, re)
+	}
+	return strings.TrimSpace(line)
+}
+
+// RewriteRule replaces every match of Regex in a failure line by Replace,
+// which can refer to capture groups as $1 or ${name}.
+type RewriteRule struct {
+	Regex   string `json:"regex"`
+	Replace string `json:"replace"`
+}
+
+// rewrite is a compiled RewriteRule.
+type rewrite struct {
+	regex   *regexp.Regexp
+	replace string
+}
+
+func (r rewrite) apply(line string) string {
+	return r.regex.ReplaceAllString(line, r.replace)
+}
+
+// Built-in scrubbers of volatile tokens, applied in this order after the
+// rewrite rules of a target.
+const (
+	ScrubTimestamps      = "timestamps"
+	ScrubUUIDs           = "uuids"
+	ScrubKuttlNamespaces = "kuttl-namespaces"
+	ScrubPodHashes       = "pod-hashes"
+	ScrubDurations       = "durations"
+)
+
+// podHashChars are the characters of generated Kubernetes name suffixes, no
+// vowels so that words are left alone.
+const podHashChars = `[bcdfghjklmnpqrstvwxz2456789]`
+
+var scrubbers = []struct {
+	name string
+	rewrite
+}{
+	// 2024-01-02T10:00:00.123Z, 2024-01-02 10:00:00 and 10:00:00.123
+	{ScrubTimestamps, rewrite{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\b\d{2}:\d{2}:\d{2}(?:\.\d+)?\b`), "<timestamp>"}},
+	{ScrubUUIDs, rewrite{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"}},
+	// kuttl-test-fitting-cicada
+	{ScrubKuttlNamespaces, rewrite{regexp.MustCompile(`\bkuttl-test-[a-z0-9]+(?:-[a-z0-9]+)*`), "kuttl-test-<random>"}},
+	// argocd-server-7d9f8b6c4-x2k9p, argocd-server-x2k9p
+	{ScrubPodHashes, rewrite{regexp.MustCompile(`-(?:` + podHashChars + `{8,10}-)?` + podHashChars + `{5}\b`), "-<hash>"}},
+	// 1.5s, 250ms, 1h0m0s
+	{ScrubDurations, rewrite{regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))*\b`), "<duration>"}},
+}
+
+// Normalizer turns a failure line into the canonical key failures are
+// counted by, so that lines only differing by volatile tokens are one test.
+type Normalizer struct {
+	rewrites []rewrite
+}
+
+// NewNormalizer compiles rules followed by the named scrubbers. Nil
+// scrubbers means all of them, an empty list none.
+func NewNormalizer(rules []RewriteRule, enabled []string) (*Normalizer, error) {
+	n := &Normalizer{}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite regex %q: %w", rule.Regex, err)
+		}
+		n.rewrites = append(n.rewrites, rewrite{re, rule.Replace})
+	}
+
+	for _, name := range enabled {
+		if !containsString(scrubberNames(), name) {
+			return nil, fmt.Errorf("unknown scrubber %q, must be one of %s", name, strings.Join(scrubberNames(), ", "))
+		}
+	}
+	for _, s := range scrubbers {
+		if enabled == nil || containsString(enabled, s.name) {
+			n.rewrites = append(n.rewrites, s.rewrite)
+		}
+	}
+	return n, nil
+}
+
+// Key applies the rewrite rules and scrubbers to a cleaned failure line.
+func (n *Normalizer) Key(line string) string {
+	for _, r := range n.rewrites {
+		line = r.apply(line)
+	}
+	return strings.TrimSpace(line)
+}
+
+// scrubberNames lists the built-in scrubbers in the order they apply.
+func scrubberNames() []string {
+	names := []string{}
+	for _, s := range scrubbers {
+		names = append(names, s.name)
+	}
+	return names
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultStorageURL is the base URL prow job artifacts are downloaded from.
+const DefaultStorageURL = "https://storage.googleapis.com"
+
+// prowViewURL is where prow renders the runs of a bucket.
+const prowViewURL = "https://prow.ci.openshift.org/view/gs"
+
+// ProwRun identifies a single prow job run. It is parsed from prow, gcsweb,
+// storage or gs:// URLs of the layouts
+//
+//	.../<bucket>/pr-logs/pull/<org>_<repo>/<pr>/<job>/<build id>
+//	.../<bucket>/pr-logs/pull/batch/<job>/<build id>
+//	.../<bucket>/logs/<job>/<build id>
+//
+// e.g. https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-openshift-odo-main-v4.8-operatorhub-integration-nightly/1429594453135331328
+type ProwRun struct {
+	Bucket string
+	// Org, Repo and PR are only set for presubmits of a single PR.
+	Org  string
+	Repo string
+	PR   int
+	// Batch is set for presubmits testing several PRs at once.
+	Batch   bool
+	Job     string
+	BuildID string
+}
+
+// bucketPrefixes are the path segments that precede the bucket name in the
+// URLs served by prow (/view/gs/), older prow (/view/gcs/), gcsweb (/gcs/)
+// and storage.googleapis.com (no prefix).
+var bucketPrefixes = [][]string{
+	{"view", "gs"},
+	{"view", "gcs"},
+	{"gcs"},
+}
+
+// ParseProwURL ...
+func ParseProwURL(rawURL string) (*ProwRun, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Scheme == "gs" {
+		// gs://<bucket>/<path>, as written to pr-logs/directory links
+		segments = append([]string{u.Host}, segments...)
+	}
+	for _, prefix := range bucketPrefixes {
+		if hasSegmentPrefix(segments, prefix) {
+			segments = segments[len(prefix):]
+			break
+		}
+	}
+
+	// drop a trailing artifact name, e.g. build-log.txt
+	if n := len(segments); n > 0 && strings.Contains(segments[n-1], ".") {
+		segments = segments[:n-1]
+	}
+
+	if len(segments) < 4 {
+		return nil, fmt.Errorf("unrecognized prow URL %q", rawURL)
+	}
+
+	run := &ProwRun{Bucket: segments[0]}
+	rest := segments[1:]
+
+	switch {
+	case len(rest) == 3 && rest[0] == "logs":
+		run.Job, run.BuildID = rest[1], rest[2]
+
+	case len(rest) == 5 && rest[0] == "pr-logs" && rest[1] == "pull" && rest[2] == "batch":
+		run.Batch = true
+		run.Job, run.BuildID = rest[3], rest[4]
+
+	case len(rest) == 6 && rest[0] == "pr-logs" && rest[1] == "pull":
+		// GitHub org names cannot contain "_", repo names can
+		orgRepo := strings.SplitN(rest[2], "_", 2)
+		if len(orgRepo) != 2 {
+			return nil, fmt.Errorf("unrecognized org_repo %q in prow URL %q", rest[2], rawURL)
+		}
+		pr, err := strconv.Atoi(rest[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PR number %q in prow URL %q", rest[3], rawURL)
+		}
+		run.Org, run.Repo, run.PR = orgRepo[0], orgRepo[1], pr
+		run.Job, run.BuildID = rest[4], rest[5]
+
+	default:
+		return nil, fmt.Errorf("unrecognized prow URL %q", rawURL)
+	}
+
+	if _, err := strconv.ParseUint(run.BuildID, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid build id %q in prow URL %q", run.BuildID, rawURL)
+	}
+
+	return run, nil
+}
+
+func hasSegmentPrefix(segments, prefix []string) bool {
+	if len(segments) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if segments[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Path is the object path of the run's artifacts inside its bucket.
+func (r ProwRun) Path() string {
+	switch {
+	case r.Batch:
+		return fmt.Sprintf("pr-logs/pull/batch/%s/%s", r.Job, r.BuildID)
+	case r.PR != 0:
+		return fmt.Sprintf("pr-logs/pull/%s_%s/%d/%s/%s", r.Org, r.Repo, r.PR, r.Job, r.BuildID)
+	}
+	return fmt.Sprintf("logs/%s/%s", r.Job, r.BuildID)
+}
+
+// ArtifactURL returns the URL of the named artifact (e.g. "build-log.txt")
+// of the run under storageURL, or DefaultStorageURL when it is empty.
+func (r ProwRun) ArtifactURL(storageURL, name string) string {
+	return storageBaseURL(storageURL) + "/" + r.Bucket + "/" + r.Path() + "/" + name
+}
+
+// ViewURL is the prow page of the run.
+func (r ProwRun) ViewURL() string {
+	return prowViewURL + "/" + r.Bucket + "/" + r.Path()
+}
+
+// storageBaseURL returns storageURL without trailing slash, or
+// DefaultStorageURL when it is empty.
+func storageBaseURL(storageURL string) string {
+	if storageURL == "" {
+		storageURL = DefaultStorageURL
+	}
+	return strings.TrimSuffix(storageURL, "/")
+}
+
+// BuildLogURL ...
+func (r ProwRun) BuildLogURL(storageURL string) string {
+	return r.ArtifactURL(storageURL, "build-log.txt")
+}
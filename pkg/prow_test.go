@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProwURL(t *testing.T) {
+	const (
+		job      = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+		periodic = "periodic-ci-openshift-odo-main-v4.8-operatorhub-integration-nightly"
+	)
+	pull := &ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: 101, Job: job, BuildID: "1700000000000000001"}
+	batch := &ProwRun{Bucket: "test-platform-results", Batch: true, Job: job, BuildID: "1700000000000000002"}
+	logs := &ProwRun{Bucket: "origin-ci-test", Job: periodic, BuildID: "1429594453135331328"}
+
+	tests := []struct {
+		name string
+		url  string
+		want *ProwRun
+	}{
+		{"view gs", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001", pull},
+		{"view gcs", "https://prow.ci.openshift.org/view/gcs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001", pull},
+		{"gcsweb", "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/", pull},
+		{"storage", "https://storage.googleapis.com/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001", pull},
+		{"gs", "gs://test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001", pull},
+		{"build log", "https://storage.googleapis.com/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/build-log.txt", pull},
+		{"query string", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001?tab=logs#L10", pull},
+		{"underscore in repo", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_release_tools/7/" + job + "/1700000000000000001",
+			&ProwRun{Bucket: "test-platform-results", Org: "openshift", Repo: "release_tools", PR: 7, Job: job, BuildID: "1700000000000000001"}},
+		{"batch", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/batch/" + job + "/1700000000000000002", batch},
+		{"logs", "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/" + periodic + "/1429594453135331328", logs},
+		{"logs build log", "https://storage.googleapis.com/origin-ci-test/logs/" + periodic + "/1429594453135331328/build-log.txt", logs},
+
+		{"bad PR number", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/abc/" + job + "/1700000000000000001", nil},
+		{"bad build id", "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/" + periodic + "/latest", nil},
+		{"missing org_repo separator", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/gitops-operator/101/" + job + "/1700000000000000001", nil},
+		{"unknown layout", "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/directory/" + job + "/1700000000000000001", nil},
+		{"too short", "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/" + periodic, nil},
+		{"not a URL", "://", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProwURL(tt.url)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("ParseProwURL(%q) = %+v, want an error", tt.url, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseProwURL(%q): %v", tt.url, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseProwURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProwRunURLs(t *testing.T) {
+	const job = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	tests := []struct {
+		run       ProwRun
+		path      string
+		artifact  string
+		viewURL   string
+		customURL string
+	}{
+		{
+			run:       ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: 101, Job: job, BuildID: "1700000000000000001"},
+			path:      "pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001",
+			artifact:  "https://storage.googleapis.com/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/build-log.txt",
+			viewURL:   "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001",
+			customURL: "http://127.0.0.1:4443/test-platform-results/pr-logs/pull/redhat-developer_gitops-operator/101/" + job + "/1700000000000000001/started.json",
+		},
+		{
+			run:       ProwRun{Bucket: "test-platform-results", Batch: true, Job: job, BuildID: "1700000000000000002"},
+			path:      "pr-logs/pull/batch/" + job + "/1700000000000000002",
+			artifact:  "https://storage.googleapis.com/test-platform-results/pr-logs/pull/batch/" + job + "/1700000000000000002/build-log.txt",
+			viewURL:   "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/batch/" + job + "/1700000000000000002",
+			customURL: "http://127.0.0.1:4443/test-platform-results/pr-logs/pull/batch/" + job + "/1700000000000000002/started.json",
+		},
+		{
+			run:       ProwRun{Bucket: "origin-ci-test", Job: "periodic-job", BuildID: "1429594453135331328"},
+			path:      "logs/periodic-job/1429594453135331328",
+			artifact:  "https://storage.googleapis.com/origin-ci-test/logs/periodic-job/1429594453135331328/build-log.txt",
+			viewURL:   "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/periodic-job/1429594453135331328",
+			customURL: "http://127.0.0.1:4443/origin-ci-test/logs/periodic-job/1429594453135331328/started.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := tt.run.Path(); got != tt.path {
+				t.Errorf("Path() = %q, want %q", got, tt.path)
+			}
+			if got := tt.run.ArtifactURL("", "build-log.txt"); got != tt.artifact {
+				t.Errorf("ArtifactURL() = %q, want %q", got, tt.artifact)
+			}
+			if got := tt.run.ArtifactURL("http://127.0.0.1:4443/", "started.json"); got != tt.customURL {
+				t.Errorf("ArtifactURL(custom) = %q, want %q", got, tt.customURL)
+			}
+			if got := tt.run.ViewURL(); got != tt.viewURL {
+				t.Errorf("ViewURL() = %q, want %q", got, tt.viewURL)
+			}
+
+			// every URL built from a run parses back to it
+			for _, u := range []string{tt.run.ViewURL(), tt.run.ArtifactURL("", "build-log.txt"), tt.run.BuildLogURL("http://127.0.0.1:4443")} {
+				parsed, err := ParseProwURL(u)
+				if err != nil {
+					t.Fatalf("ParseProwURL(%q): %v", u, err)
+				}
+				if !reflect.DeepEqual(*parsed, tt.run) {
+					t.Errorf("ParseProwURL(%q) = %+v, want %+v", u, *parsed, tt.run)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// Report is the typed result of an analysis run. Renderers turn it into
+// markdown or any other output format.
+type Report struct {
+	GeneratedAt time.Time
+	Targets     []TargetReport
+	// Problems are failures of a whole stage, e.g. a search that could not
+	// be run. Per-run problems are kept in their Section.
+	Problems []DataProblem
+}
+
+// Target identifies a repository a report was generated for.
+type Target struct {
+	RepoOrg  string
+	RepoName string
+}
+
+func (t Target) String() string {
+	return t.RepoOrg + "/" + t.RepoName
+}
+
+// TargetReport holds the sections of one target, one per job kind.
+type TargetReport struct {
+	Target   Target
+	Sections []Section
+}
+
+// SummaryEntry is a test failure ranked across all targets.
+type SummaryEntry struct {
+	Target Target
+	Kind   string
+	Test   TestReport
+}
+
+// Section holds the failures found for one job kind.
+type Section struct {
+	Kind  string
+	Title string
+	// Window is the search maxAge the section covers.
+	Window time.Duration
+	// Categories of the target's patterns, in configuration order.
+	Categories []string
+	Tests      []TestReport
+	// Signatures cluster the failure messages of Tests.
+	Signatures []FailureSignature
+	// JUnit aggregates the junit outcomes of the section's runs.
+	JUnit []JUnitStats
+	// JUnitAllRuns is set when JUnit counts every run of the jobs with a
+	// history rather than only the failed runs found by search.
+	JUnitAllRuns bool
+	// Infra counts the infra runs of the jobs of the section.
+	Infra []InfraSummary
+	// Steps aggregates the kuttl step results of the section's runs.
+	Steps []KuttlStepStats
+	// Jobs holds the run history of every job with failures, when enabled.
+	Jobs     []JobHistory
+	Problems []DataProblem
+}
+
+// TestReport is one failing test, ordered in its section by Score.
+type TestReport struct {
+	Name string
+	// Pattern and Category are the name and category of the pattern that
+	// found the failure, empty for a target without patterns.
+	Pattern  string
+	Category string
+	// Message and Location describe the first failure, when the pattern
+	// has an extractor.
+	Message  string
+	Location string
+	// Excerpt is the build log around the most recent failure, when enabled.
+	Excerpt  *Excerpt
+	Score    int
+	Fails    int
+	LastSeen *time.Time
+	// Branches the failure was seen on.
+	Branches []string
+	Groups   []RunGroup
+	// JUnit is the junit outcome of the same test, if one was found.
+	JUnit *JUnitStats
+	// FailedRuns out of TotalRuns runs of the jobs the test failed in, when
+	// job histories are enabled.
+	FailedRuns int
+	TotalRuns  int
+	// Retest tells whether retests of the failing PR commits passed.
+	Retest RetestVerdict
+	// TrackedBy lists the bugs and issues mentioning the test, when enabled.
+	TrackedBy []BugLink
+	// Breakdown counts the failing runs per version, platform and variant.
+	Breakdown Breakdown
+}
+
+// FailureRate is FailedRuns/TotalRuns, or 0 without a job history.
+func (t TestReport) FailureRate() float64 {
+	if t.TotalRuns == 0 {
+		return 0
+	}
+	return float64(t.FailedRuns) / float64(t.TotalRuns)
+}
+
+// RunGroup collects the failed runs of a test for one group key, e.g. a pull
+// request number or a periodic cluster version.
+type RunGroup struct {
+	Key   string
+	Label string
+	URL   string
+	Runs  []Run
+}
+
+// Run is a single prow job run a failure was seen in.
+type Run struct {
+	URL string
+	// Prow is nil when URL could not be parsed.
+	Prow        *ProwRun
+	JobName     string
+	Branch      string
+	BuildLogURL string
+	// Time is when the run started, from started.json or else from the
+	// first line of the build log.
+	Time *time.Time
+
+	// Prow job metadata, see fetchMetadata.
+	Started  *time.Time
+	Finished *time.Time
+	Result   string
+	Duration time.Duration
+	Refs     *Refs
+	// Labels are the labels of the prow job.
+	Labels map[string]string
+
+	// Class tells whether the run failed because of the infrastructure, by
+	// the classifier rule ClassRule.
+	Class     RunClass
+	ClassRule string
+
+	// Dimensions are parsed from the job name and labels.
+	Dimensions JobDimensions
+
+	// JUnit holds the test cases of the run's junit artifacts, when enabled.
+	JUnit []TestCaseResult
+	// KuttlSteps holds the kuttl step results of the build log, when enabled.
+	KuttlSteps []KuttlStepResult
+}
+
+// Stages of the analysis a DataProblem can be attributed to.
+const (
+	StageSearch   = "search"
+	StageURL      = "url"
+	StageDownload = "download"
+	StageParse    = "parse"
+	StageMetadata = "metadata"
+	StageJUnit    = "junit"
+	StageHistory  = "history"
+	StageRetest   = "retest"
+	StageBugs     = "bugs"
+)
+
+// DataProblem describes data that could not be fetched or parsed, and why.
+type DataProblem struct {
+	Target string
+	Kind   string
+	Stage  string
+	RunURL string
+	Err    string
+}
+
+// ReportStatus tells whether a report is based on all the data it asked for.
+type ReportStatus int
+
+const (
+	// ReportComplete means no problems were recorded.
+	ReportComplete ReportStatus = iota
+	// ReportPartial means some runs or job kinds are missing from the report.
+	ReportPartial
+	// ReportFailed means no job kind could be analyzed at all.
+	ReportFailed
+)
+
+func (s ReportStatus) String() string {
+	switch s {
+	case ReportComplete:
+		return "complete"
+	case ReportPartial:
+		return "partial"
+	}
+	return "failed"
+}
+
+// ExitCode is the process exit code for the status: 0 when complete, 1 when
+// failed and 2 when partial.
+func (s ReportStatus) ExitCode() int {
+	switch s {
+	case ReportComplete:
+		return 0
+	case ReportPartial:
+		return 2
+	}
+	return 1
+}
+
+// AllProblems returns the report level problems followed by the problems of
+// every section.
+func (r *Report) AllProblems() []DataProblem {
+	problems := append([]DataProblem{}, r.Problems...)
+	for _, target := range r.Targets {
+		for _, section := range target.Sections {
+			problems = append(problems, section.Problems...)
+		}
+	}
+	return problems
+}
+
+// FilterCategories drops the tests of every category not listed. An empty
+// list keeps everything.
+func (r *Report) FilterCategories(categories []string) {
+	if len(categories) == 0 {
+		return
+	}
+	for i := range r.Targets {
+		for j := range r.Targets[i].Sections {
+			section := &r.Targets[i].Sections[j]
+
+			kept := []string{}
+			for _, category := range section.Categories {
+				if containsString(categories, category) {
+					kept = append(kept, category)
+				}
+			}
+			section.Categories = kept
+
+			tests := []TestReport{}
+			for _, test := range section.Tests {
+				if containsString(categories, test.Category) {
+					tests = append(tests, test)
+				}
+			}
+			section.Tests = tests
+
+			signatures := []FailureSignature{}
+			for _, signature := range section.Signatures {
+				tests := []SignatureTest{}
+				for _, test := range signature.Tests {
+					if containsString(categories, test.Category) {
+						tests = append(tests, test)
+					}
+				}
+				if len(tests) > 0 {
+					signature.Tests = tests
+					signature.Examples = signatureExampleRuns(tests)
+					signatures = append(signatures, signature)
+				}
+			}
+			section.Signatures = signatures
+		}
+	}
+}
+
+// Summary ranks the failures of every section of every target, worst first.
+func (r *Report) Summary() []SummaryEntry {
+	entries := []SummaryEntry{}
+	for _, target := range r.Targets {
+		for _, section := range target.Sections {
+			for _, test := range section.Tests {
+				entries = append(entries, SummaryEntry{Target: target.Target, Kind: section.Kind, Test: test})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lessTest(entries[i].Test, entries[j].Test)
+	})
+	return entries
+}
+
+// Status ...
+func (r *Report) Status() ReportStatus {
+	sections := 0
+	for _, target := range r.Targets {
+		sections += len(target.Sections)
+	}
+
+	if sections == 0 && len(r.Problems) > 0 {
+		return ReportFailed
+	}
+	if len(r.AllProblems()) > 0 {
+		return ReportPartial
+	}
+	return ReportComplete
+}
+
+// Renderer writes a Report in some output format.
+type Renderer interface {
+	Render(w io.Writer, report *Report) error
+}
+
+// NewReport ...
+func NewReport() *Report {
+	return &Report{
+		GeneratedAt: time.Now(),
+	}
+}
+
+// GenerateReport runs an Analyzer for every job kind of every target in the
+// config and collects the results in a single Report. The cache and HTTP
+// clients are shared by all targets. A job kind that cannot be analyzed is
+// recorded as a problem on the report; the returned error is reserved for
+// setup failures.
+func GenerateReport(ctx context.Context, config Config, kinds ...JobKind) (*Report, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	blobStorage, err := NewBlobStorage("./.cache")
+	if err != nil {
+		return nil, err
+	}
+
+	// one search client per search.ci instance, all sharing an HTTP client
+	httpClient := &http.Client{}
+	searchClients := map[string]*HTTPSearchClient{}
+
+	return generateReport(ctx, config, kinds, func(targetConfig Config, kind JobKind) *Analyzer {
+		searchClient, ok := searchClients[targetConfig.SearchURL]
+		if !ok {
+			searchClient = NewSearchClient(targetConfig.SearchURL)
+			searchClient.Client = httpClient
+			searchClients[targetConfig.SearchURL] = searchClient
+		}
+		analyzer := NewAnalyzer(targetConfig, kind, blobStorage, searchClient)
+		analyzer.HTTPClient = httpClient
+		return analyzer
+	}), nil
+}
+
+// GenerateLocalReport analyzes the build logs of a directory or .tar.gz
+// archive instead of querying search.ci, see LocalSource.
+func GenerateLocalReport(ctx context.Context, config Config, localPath string, kinds ...JobKind) (*Report, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	source, err := OpenLocal(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	// a throwaway cache, local files may change between runs
+	cacheDir, err := os.MkdirTemp("", "flake-dashboard-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(cacheDir)
+	blobStorage, err := NewBlobStorage(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := generateReport(ctx, config, kinds, func(targetConfig Config, kind JobKind) *Analyzer {
+		analyzer := NewAnalyzer(targetConfig, kind, blobStorage, source)
+		analyzer.HTTPClient = source.Client()
+		return analyzer
+	})
+	report.Problems = append(source.Problems, report.Problems...)
+	return report, nil
+}
+
+// generateReport runs the analyzer returned by newAnalyzer for every target
+// and kind.
+func generateReport(ctx context.Context, config Config, kinds []JobKind, newAnalyzer func(Config, JobKind) *Analyzer) *Report {
+	report := NewReport()
+	for _, targetConfig := range config.AllTargets() {
+		target := TargetReport{Target: Target{RepoOrg: targetConfig.RepoOrg, RepoName: targetConfig.RepoName}}
+
+		for _, kind := range kinds {
+			section, err := newAnalyzer(targetConfig, kind).Analyze(ctx)
+			if err != nil {
+				report.Problems = append(report.Problems, DataProblem{Target: target.Target.String(), Kind: kind.Name(), Stage: StageSearch, Err: err.Error()})
+				continue
+			}
+			target.Sections = append(target.Sections, *section)
+		}
+
+		report.Targets = append(report.Targets, target)
+	}
+
+	return report
+}
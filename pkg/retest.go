@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// RetestVerdict classifies a test failure by what happened when the same
+// commit of the same PR was tested again.
+type RetestVerdict string
+
+const (
+	// RetestUnknown means no retest of a failing commit is known.
+	RetestUnknown RetestVerdict = "unknown"
+	// RetestConfirmedFlake means a run that failed was followed by a passing
+	// run of the same job on the same PR head commit.
+	RetestConfirmedFlake RetestVerdict = "confirmed flake"
+	// RetestConsistent means every retest of the failing commit failed the
+	// test again, which points at the PR rather than at a flake.
+	RetestConsistent RetestVerdict = "consistent on this PR"
+)
+
+// confirmedFlakeWeight multiplies the score of confirmed flakes.
+const confirmedFlakeWeight = 2
+
+// retestKey identifies the runs that tested the same commit of a PR with the
+// same job.
+type retestKey struct {
+	pr  int
+	job string
+	sha string
+}
+
+func retestKeyOf(run Run) (retestKey, bool) {
+	if run.Prow == nil || run.Prow.PR == 0 {
+		return retestKey{}, false
+	}
+	sha := run.Refs.HeadSHA()
+	if sha == "" {
+		return retestKey{}, false
+	}
+	return retestKey{pr: run.Prow.PR, job: run.Prow.Job, sha: sha}, true
+}
+
+// retestVerdict classifies a test from the runs it failed in and all other
+// known runs: the fetched runs and the job histories or, without them, the PR
+// retests. Passing runs are only known from the latter.
+func retestVerdict(failures []Run, known []Run) RetestVerdict {
+	failed := map[retestKey]map[string]bool{}
+	for _, run := range failures {
+		key, ok := retestKeyOf(run)
+		if !ok {
+			continue
+		}
+		if failed[key] == nil {
+			failed[key] = map[string]bool{}
+		}
+		failed[key][run.Prow.BuildID] = true
+	}
+	if len(failed) == 0 {
+		return RetestUnknown
+	}
+
+	byKey := map[retestKey]map[string]Run{}
+	for _, run := range append(append([]Run{}, known...), failures...) {
+		key, ok := retestKeyOf(run)
+		if !ok || failed[key] == nil {
+			continue
+		}
+		if byKey[key] == nil {
+			byKey[key] = map[string]Run{}
+		}
+		// keep the copy with a result, the failures may lack metadata
+		if existing, ok := byKey[key][run.Prow.BuildID]; !ok || existing.Result == "" {
+			byKey[key][run.Prow.BuildID] = run
+		}
+	}
+
+	verdict := RetestUnknown
+	for key, runs := range byKey {
+		switch retestSequence(runs, failed[key]) {
+		case RetestConfirmedFlake:
+			return RetestConfirmedFlake
+		case RetestConsistent:
+			verdict = RetestConsistent
+		}
+	}
+	return verdict
+}
+
+// retestSequence walks the runs of one PR commit in build order, starting at
+// the first failure of the test.
+func retestSequence(runs map[string]Run, failed map[string]bool) RetestVerdict {
+	builds := []string{}
+	for build := range runs {
+		builds = append(builds, build)
+	}
+	// prow build ids increase monotonically
+	sort.Slice(builds, func(i, j int) bool {
+		one, _ := strconv.ParseUint(builds[i], 10, 64)
+		two, _ := strconv.ParseUint(builds[j], 10, 64)
+		return one < two
+	})
+
+	failures := 0
+	consistent := true
+	for _, build := range builds {
+		if failed[build] {
+			failures++
+			continue
+		}
+		if failures == 0 {
+			continue
+		}
+		if passed, finished := runPassed(runs[build]); finished && passed {
+			return RetestConfirmedFlake
+		}
+		// failed for another reason, or did not finish
+		consistent = false
+	}
+
+	if consistent && failures > 1 {
+		return RetestConsistent
+	}
+	return RetestUnknown
+}
+
+// prRetests finds the retests of the failing PR runs without a job history,
+// when Config.Retests is set: it lists the runs of each failing job on each
+// PR, from pr-logs/pull/<org>_<repo>/<pr>/<job>/, and fetches the metadata of
+// those started after the first failure that are not known yet. That is one
+// listing per failing job and PR, and the metadata of every later run.
+func (a *Analyzer) prRetests(ctx context.Context, known []Run) ([]Run, []DataProblem) {
+	// the earliest failure of each job on each PR
+	first := map[string]ProwRun{}
+	seen := map[string]bool{}
+	for _, run := range known {
+		if run.Prow == nil {
+			continue
+		}
+		seen[run.Prow.Path()] = true
+		if run.Prow.PR == 0 || run.Prow.Batch {
+			continue
+		}
+		dir := path.Dir(run.Prow.Path())
+		if existing, ok := first[dir]; !ok || buildNumber(run.Prow.BuildID) < buildNumber(existing.BuildID) {
+			first[dir] = *run.Prow
+		}
+	}
+
+	dirs := []string{}
+	for dir := range first {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	storage := a.storageClient()
+	candidates := []historyCandidate{}
+	problems := []DataProblem{}
+	for _, dir := range dirs {
+		sample := first[dir]
+		list, err := storage.List(ctx, sample.Bucket, dir+"/", "/")
+		if err != nil {
+			problems = append(problems, a.problem(StageRetest, storage.ObjectURL(sample.Bucket, dir+"/"), err))
+			continue
+		}
+		for _, prefix := range list.Prefixes {
+			retest := sample
+			retest.BuildID = path.Base(prefix)
+			if _, err := strconv.ParseUint(retest.BuildID, 10, 64); err != nil {
+				continue
+			}
+			if buildNumber(retest.BuildID) <= buildNumber(sample.BuildID) || seen[retest.Path()] {
+				continue
+			}
+			candidates = append(candidates, historyCandidate{run: retest})
+		}
+	}
+
+	retests := make([]Run, len(candidates))
+	retestProblems := make([][]DataProblem, len(candidates))
+	a.forEachIndex(len(candidates), func(index int) {
+		retests[index], retestProblems[index] = a.historyRun(ctx, candidates[index])
+	})
+
+	for _, p := range retestProblems {
+		problems = append(problems, p...)
+	}
+	return retests, problems
+}
+
+// buildNumber is the numeric value of a prow build id, 0 if invalid.
+func buildNumber(buildID string) uint64 {
+	n, _ := strconv.ParseUint(buildID, 10, 64)
+	return n
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPRRetests(t *testing.T) {
+	const job = "pull-ci-redhat-developer-gitops-operator-master-v4.14-kuttl-sequential"
+	run := func(pr int, id string) ProwRun {
+		return ProwRun{Bucket: "test-platform-results", Org: "redhat-developer", Repo: "gitops-operator", PR: pr, Job: job, BuildID: id}
+	}
+	objects := map[string]string{}
+	for _, r := range []ProwRun{run(101, "10"), run(101, "11"), run(101, "12"), run(102, "20")} {
+		addHistoryRun(objects, r, time.Hour, "SUCCESS")
+		objects[r.Bucket+"/"+r.Path()+"/prowjob.json"] = `{"spec": {"refs": {"org": "redhat-developer", "repo": "gitops-operator", "pulls": [{"number": 1, "sha": "abc"}]}}}`
+	}
+	fake, server := newFakeGCS(t, objects)
+	a := historyAnalyzer(t, server, PullJobs)
+
+	// 11 failed and is known, 12 retested it; 10 ran before the failure and
+	// PR 102 did not fail
+	failure := run(101, "11")
+	failures := []Run{{URL: failure.ViewURL(), Prow: &failure, Refs: &Refs{Pulls: []Pull{{Number: 101, SHA: "abc"}}}, Result: "FAILURE"}}
+	retests, problems := a.prRetests(context.Background(), failures)
+	if len(problems) != 0 {
+		t.Errorf("prRetests() problems = %+v", problems)
+	}
+	if len(retests) != 1 || retests[0].Prow.BuildID != "12" || retests[0].Result != "SUCCESS" || retests[0].Refs.HeadSHA() != "abc" {
+		t.Fatalf("prRetests() = %+v, want the passing run 12", retests)
+	}
+	for _, r := range []ProwRun{run(101, "10"), run(102, "20")} {
+		if fake.wasDownloaded(r.Bucket + "/" + r.Path() + "/started.json") {
+			t.Errorf("prRetests() fetched %s, which is no retest of a failure", r.Path())
+		}
+	}
+
+	if verdict := retestVerdict(failures, retests); verdict != RetestConfirmedFlake {
+		t.Errorf("retestVerdict() = %q, want %q", verdict, RetestConfirmedFlake)
+	}
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSearchURL is the base URL of the OpenShift CI search service.
+const DefaultSearchURL = "https://search.ci.openshift.org"
+
+// SearchType selects the kind of artifacts search.ci looks through.
+type SearchType string
+
+const (
+	SearchTypeBuildLog SearchType = "build-log"
+	// SearchTypeJUnit searches the failure output of junit test cases.
+	SearchTypeJUnit SearchType = "junit"
+	// SearchTypeBug and SearchTypeIssue search Bugzilla bugs and Jira
+	// issues.
+	SearchTypeBug   SearchType = "bug"
+	SearchTypeIssue SearchType = "issue"
+)
+
+// SearchQuery holds the options understood by the search.ci /search endpoint.
+// Zero values are left out of the request, except for Context.
+type SearchQuery struct {
+	// Search holds one or more regexes, searched in one pass. Results are
+	// keyed by the regex that matched.
+	Search      []string
+	Type        SearchType
+	Context     int
+	MaxAge      time.Duration
+	MaxMatches  int
+	MaxBytes    int64
+	Name        string
+	ExcludeName string
+	GroupBy     string
+}
+
+// Values encodes the query as URL parameters.
+func (q SearchQuery) Values() url.Values {
+	values := url.Values{}
+	for _, search := range q.Search {
+		values.Add("search", search)
+	}
+	values.Set("context", strconv.Itoa(q.Context))
+	if q.Type != "" {
+		values.Set("type", string(q.Type))
+	}
+	if q.MaxAge > 0 {
+		values.Set("maxAge", formatMaxAge(q.MaxAge))
+	}
+	if q.MaxMatches > 0 {
+		values.Set("maxMatches", strconv.Itoa(q.MaxMatches))
+	}
+	if q.MaxBytes > 0 {
+		values.Set("maxBytes", strconv.FormatInt(q.MaxBytes, 10))
+	}
+	if q.Name != "" {
+		values.Set("name", q.Name)
+	}
+	if q.ExcludeName != "" {
+		values.Set("excludeName", q.ExcludeName)
+	}
+	if q.GroupBy != "" {
+		values.Set("groupBy", q.GroupBy)
+	}
+	return values
+}
+
+// formatMaxAge renders whole hours the way search.ci links do ("336h").
+func formatMaxAge(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int64(d/time.Hour))
+	}
+	return d.String()
+}
+
+// SearchClient runs queries against a search.ci instance.
+type SearchClient interface {
+	Search(ctx context.Context, query SearchQuery) (Result, error)
+}
+
+// HTTPSearchClient is a SearchClient talking to search.ci over HTTP.
+type HTTPSearchClient struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSearchClient returns a client for the search.ci instance at baseURL,
+// or DefaultSearchURL when baseURL is empty.
+func NewSearchClient(baseURL string) *HTTPSearchClient {
+	if baseURL == "" {
+		baseURL = DefaultSearchURL
+	}
+	return &HTTPSearchClient{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{},
+	}
+}
+
+// Search ...
+func (c *HTTPSearchClient) Search(ctx context.Context, query SearchQuery) (Result, error) {
+	var result Result
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/search", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// https://search.ci.openshift.org/search?context=0&maxAge=336h&maxBytes=20971520&maxMatches=5&name=pull-ci-openshift-odo-main-&search=%5C%5BFail%5C%5D&type=build-log
+	req.URL.RawQuery = query.Values().Encode()
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search %s: unexpected status %s", req.URL, resp.Status)
+	}
+
+	byteValue, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(byteValue, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
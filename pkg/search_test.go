@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchQueryValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  url.Values
+	}{
+		{
+			// context is always sent, 0 asks for the matching lines only
+			name:  "empty",
+			query: SearchQuery{},
+			want:  url.Values{"context": {"0"}},
+		},
+		{
+			name: "all options",
+			query: SearchQuery{
+				Search:      []string{`\[Fail\]`, "--- FAIL: kuttl/harness/"},
+				Type:        SearchTypeBuildLog,
+				Context:     2,
+				MaxAge:      14 * 24 * time.Hour,
+				MaxMatches:  5,
+				MaxBytes:    20971520,
+				Name:        "pull-ci-openshift-odo-main-",
+				ExcludeName: "e2e-aws",
+				GroupBy:     "job",
+			},
+			want: url.Values{
+				"search":      {`\[Fail\]`, "--- FAIL: kuttl/harness/"},
+				"type":        {"build-log"},
+				"context":     {"2"},
+				"maxAge":      {"336h"},
+				"maxMatches":  {"5"},
+				"maxBytes":    {"20971520"},
+				"name":        {"pull-ci-openshift-odo-main-"},
+				"excludeName": {"e2e-aws"},
+				"groupBy":     {"job"},
+			},
+		},
+		{
+			name:  "fractional max age",
+			query: SearchQuery{MaxAge: 90 * time.Minute},
+			want:  url.Values{"context": {"0"}, "maxAge": {"1h30m0s"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.query.Values(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Values() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPSearchClient(t *testing.T) {
+	const runURL = "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/1"
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query()
+		if query.Get("name") == "broken" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"` + runURL + `": {"--- FAIL": [{"filename": "build-log", "context": ["--- FAIL: kuttl/harness/1-001_foo (1.00s)"], "moreLines": 3}]}}`))
+	}))
+	defer server.Close()
+	client := NewSearchClient(server.URL + "/")
+	client.Client = server.Client()
+
+	result, err := client.Search(context.Background(), SearchQuery{Search: []string{"--- FAIL", "panic:"}, MaxAge: 336 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (url.Values{"search": {"--- FAIL", "panic:"}, "context": {"0"}, "maxAge": {"336h"}}); !reflect.DeepEqual(query, want) {
+		t.Errorf("Search() sent %v, want %v", query, want)
+	}
+	want := Result{runURL: {"--- FAIL": {{FileType: "build-log", Context: []string{"--- FAIL: kuttl/harness/1-001_foo (1.00s)"}, MoreLines: 3}}}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Search() = %+v, want %+v", result, want)
+	}
+
+	if _, err := client.Search(context.Background(), SearchQuery{Name: "broken"}); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Search() error = %v, want the unexpected status", err)
+	}
+}
+
+func TestSearchTrackerInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch SearchType(r.URL.Query().Get("type")) {
+		case SearchTypeBug:
+			w.Write([]byte(`{"https://bugzilla.redhat.com/show_bug.cgi?id=2012345": {"1-085_validate_sync": [{"filename": "bug", "context": ["fails in 1-085_validate_sync"], "bugInfo": {"name": "Bug 2012345: sync test flakes", "status": "NEW"}}]}}`))
+		case SearchTypeIssue:
+			w.Write([]byte(`{"https://issues.redhat.com/browse/GITOPS-1234": {"1-085_validate_sync": [{"filename": "issue", "issueInfo": {"name": "1-085_validate_sync is flaky"}}]}}`))
+		}
+	}))
+	defer server.Close()
+	client := NewSearchClient(server.URL)
+	client.Client = server.Client()
+
+	bugs, err := client.Search(context.Background(), SearchQuery{Type: SearchTypeBug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := bugs["https://bugzilla.redhat.com/show_bug.cgi?id=2012345"]["1-085_validate_sync"][0]
+	if match.Issue != nil || match.Bug == nil || *match.Bug != (TrackerInfo{Name: "Bug 2012345: sync test flakes", Status: "NEW"}) {
+		t.Errorf("Search(bug) match = %+v, want the bug info", match)
+	}
+
+	issues, err := client.Search(context.Background(), SearchQuery{Type: SearchTypeIssue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match = issues["https://issues.redhat.com/browse/GITOPS-1234"]["1-085_validate_sync"][0]
+	if match.Bug != nil || match.Issue == nil || *match.Issue != (TrackerInfo{Name: "1-085_validate_sync is flaky"}) {
+		t.Errorf("Search(issue) match = %+v, want the issue info", match)
+	}
+}
+
+func TestBugID(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://bugzilla.redhat.com/show_bug.cgi?id=2012345", "Bug 2012345"},
+		{"https://issues.redhat.com/browse/OCPBUGS-1234", "OCPBUGS-1234"},
+		{"https://github.com/redhat-developer/gitops-operator/issues/1", "https://github.com/redhat-developer/gitops-operator/issues/1"},
+		{"%zz", "%zz"},
+	}
+	for _, tt := range tests {
+		if got := bugID(tt.url); got != tt.want {
+			t.Errorf("bugID(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
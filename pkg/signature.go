@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// signatureSimilarity is the minimum Jaccard similarity of the token sets of
+// two failure messages for them to share a signature.
+const signatureSimilarity = 0.6
+
+// signatureExamples is the number of example runs kept per signature.
+const signatureExamples = 3
+
+// boilerplateMessage matches the messages test frameworks print for any
+// failure, e.g. a gomega "Expected" or the exit status of a failed kuttl
+// command. They tell nothing about the cause, so they are not clustered.
+var boilerplateMessage = regexp.MustCompile(`(?i)^(?:test step failed|failed in step \S+|exit status \d+|expected|fail(?:ed)?|test failed)$`)
+
+// FailureSignature is a cluster of similar failure messages, likely one root
+// cause shared by several tests.
+type FailureSignature struct {
+	// Signature is the most frequent message of the cluster, with the tokens
+	// not common to all its messages replaced by "*".
+	Signature string
+	Tests     []SignatureTest
+	// Examples are the most recent runs failing with the signature.
+	Examples []Run
+}
+
+// SignatureTest is a test failing with a signature, and the runs it did in.
+type SignatureTest struct {
+	Name     string
+	Category string
+	Runs     []Run
+}
+
+// RunCount is the number of distinct runs failing with the signature.
+func (s FailureSignature) RunCount() int {
+	runs := map[string]bool{}
+	for _, test := range s.Tests {
+		for _, run := range test.Runs {
+			runs[run.URL] = true
+		}
+	}
+	return len(runs)
+}
+
+// occurrence is one failure of a test in a run. The message is normalized.
+type occurrence struct {
+	key     testKey
+	test    string
+	message string
+	run     Run
+}
+
+// signatureCluster collects the messages of a signature while clustering.
+type signatureCluster struct {
+	tokens   map[string]bool
+	messages []string
+}
+
+// failureSignatures clusters the messages, except boilerplate, of the
+// occurrences of the tests in keys; categories maps pattern names to
+// categories. Messages are compared by the Jaccard similarity of their tokens,
+// most frequent message first, so the result only depends on the input.
+// Signatures are sorted by descending run count.
+func failureSignatures(occurrences []occurrence, keys map[testKey]bool, categories map[string]string) []FailureSignature {
+	clustered := []occurrence{}
+	for _, o := range occurrences {
+		if keys[o.key] && !boilerplateMessage.MatchString(o.message) {
+			clustered = append(clustered, o)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, o := range clustered {
+		counts[o.message]++
+	}
+	messages := []string{}
+	for message := range counts {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if counts[messages[i]] != counts[messages[j]] {
+			return counts[messages[i]] > counts[messages[j]]
+		}
+		return messages[i] < messages[j]
+	})
+
+	clusters := []*signatureCluster{}
+	clusterOf := map[string]*signatureCluster{}
+	for _, message := range messages {
+		tokens := messageTokens(message)
+		var best *signatureCluster
+		bestSimilarity := 0.0
+		for _, c := range clusters {
+			if s := jaccard(tokens, c.tokens); s >= signatureSimilarity && s > bestSimilarity {
+				best, bestSimilarity = c, s
+			}
+		}
+		if best == nil {
+			// the first, most frequent message represents the cluster
+			best = &signatureCluster{tokens: tokens}
+			clusters = append(clusters, best)
+		}
+		best.messages = append(best.messages, message)
+		clusterOf[message] = best
+	}
+
+	signatures := []FailureSignature{}
+	for _, c := range clusters {
+		signature := FailureSignature{Signature: signatureText(c.messages)}
+		index := map[testKey]int{}
+		for _, o := range clustered {
+			if clusterOf[o.message] != c {
+				continue
+			}
+			i, ok := index[o.key]
+			if !ok {
+				i = len(signature.Tests)
+				index[o.key] = i
+				signature.Tests = append(signature.Tests, SignatureTest{Name: o.test, Category: categories[o.key.pattern]})
+			}
+			signature.Tests[i].Runs = append(signature.Tests[i].Runs, o.run)
+		}
+		sort.Slice(signature.Tests, func(i, j int) bool {
+			if signature.Tests[i].Name != signature.Tests[j].Name {
+				return signature.Tests[i].Name < signature.Tests[j].Name
+			}
+			return signature.Tests[i].Category < signature.Tests[j].Category
+		})
+		signature.Examples = signatureExampleRuns(signature.Tests)
+		signatures = append(signatures, signature)
+	}
+
+	sort.SliceStable(signatures, func(i, j int) bool {
+		return signatures[i].RunCount() > signatures[j].RunCount()
+	})
+	return signatures
+}
+
+// messageTokens returns the lower case words of a message, without
+// punctuation.
+func messageTokens(message string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, field := range strings.Fields(message) {
+		if token := signatureToken(field); token != "" {
+			tokens[token] = true
+		}
+	}
+	return tokens
+}
+
+func signatureToken(field string) string {
+	return strings.ToLower(strings.Trim(field, `.,:;"'()[]{}`))
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for token := range a {
+		if b[token] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// signatureText keeps the words of the first message found in every message,
+// and replaces each run of other words by "*".
+func signatureText(messages []string) string {
+	all := []map[string]bool{}
+	for _, message := range messages[1:] {
+		all = append(all, messageTokens(message))
+	}
+
+	words := []string{}
+	for _, field := range strings.Fields(messages[0]) {
+		common := true
+		for _, tokens := range all {
+			if !tokens[signatureToken(field)] {
+				common = false
+				break
+			}
+		}
+		if !common {
+			field = "*"
+		}
+		if field == "*" && len(words) > 0 && words[len(words)-1] == "*" {
+			continue
+		}
+		words = append(words, field)
+	}
+	return strings.Join(words, " ")
+}
+
+// signatureExampleRuns returns the most recent distinct runs of tests.
+func signatureExampleRuns(tests []SignatureTest) []Run {
+	examples := []Run{}
+	seen := map[string]bool{}
+	for _, test := range tests {
+		for _, run := range test.Runs {
+			if !seen[run.URL] {
+				seen[run.URL] = true
+				examples = append(examples, run)
+			}
+		}
+	}
+	sort.Slice(examples, func(i, j int) bool {
+		if runAfter(examples[i], examples[j]) || runAfter(examples[j], examples[i]) {
+			return runAfter(examples[i], examples[j])
+		}
+		return examples[i].URL < examples[j].URL
+	})
+	if len(examples) > signatureExamples {
+		examples = examples[:signatureExamples]
+	}
+	return examples
+}
+
+// runAfter tells whether run a started after run b, runs without a time
+// last.
+func runAfter(a, b Run) bool {
+	if a.Time == nil || b.Time == nil {
+		return a.Time != nil
+	}
+	return a.Time.After(*b.Time)
+}
@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestFailureSignatures(t *testing.T) {
+	run := func(id int) Run {
+		started := time.Date(2023, 11, 14, id, 0, 0, 0, time.UTC)
+		return Run{URL: fmt.Sprintf("https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/%d", id), Time: &started}
+	}
+	sync := testKey{pattern: "kuttl", name: "1-085_validate_sync"}
+	redis := testKey{pattern: "kuttl", name: "1-086_validate_redis"}
+	apply := testKey{pattern: "kuttl", name: "1-099_apply_manifest"}
+	wait := testKey{pattern: "kuttl", name: "1-100_wait"}
+	hidden := testKey{pattern: "kuttl", name: "1-101_single_pr"}
+	failed := func(key testKey, message string, id int) occurrence {
+		return occurrence{key: key, test: key.name, message: message, run: run(id)}
+	}
+
+	occurrences := []occurrence{
+		failed(sync, "resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0", 1),
+		failed(sync, "resource Deployment:test-1-085/guestbook: .status.readyReplicas: value mismatch, expected: 1 != actual: 0", 2),
+		failed(redis, "resource Deployment:test-1-086/redis: .status.readyReplicas: value mismatch, expected: 1 != actual: 0", 3),
+		failed(apply, `error: resource mapping not found for name: "example" from "manifest.yaml"`, 4),
+		failed(wait, "timed out waiting for the condition on argocds/example", 5),
+		// boilerplate is no root cause
+		failed(apply, "exit status 1", 6),
+		failed(wait, "exit status 1", 7),
+		failed(sync, "test step failed", 8),
+		failed(redis, "Expected", 9),
+		// not reported
+		failed(hidden, "timed out waiting for the condition on argocds/example", 10),
+	}
+	keys := map[testKey]bool{sync: true, redis: true, apply: true, wait: true}
+	categories := map[string]string{"kuttl": "e2e"}
+
+	want := []FailureSignature{
+		{
+			Signature: "resource * .status.readyReplicas: value mismatch, expected: 1 != actual: 0",
+			Tests: []SignatureTest{
+				{Name: "1-085_validate_sync", Category: "e2e", Runs: []Run{run(1), run(2)}},
+				{Name: "1-086_validate_redis", Category: "e2e", Runs: []Run{run(3)}},
+			},
+			Examples: []Run{run(3), run(2), run(1)},
+		},
+		// ties are ordered by message
+		{
+			Signature: `error: resource mapping not found for name: "example" from "manifest.yaml"`,
+			Tests:     []SignatureTest{{Name: "1-099_apply_manifest", Category: "e2e", Runs: []Run{run(4)}}},
+			Examples:  []Run{run(4)},
+		},
+		{
+			Signature: "timed out waiting for the condition on argocds/example",
+			Tests:     []SignatureTest{{Name: "1-100_wait", Category: "e2e", Runs: []Run{run(5)}}},
+			Examples:  []Run{run(5)},
+		},
+	}
+
+	got := failureSignatures(occurrences, keys, categories)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("failureSignatures() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// the order of the occurrences of different tests does not matter
+	reversed := []occurrence{}
+	for i := len(occurrences) - 1; i >= 0; i-- {
+		reversed = append(reversed, occurrences[i])
+	}
+	got = failureSignatures(reversed, keys, categories)
+	for i := range got {
+		for j := range got[i].Tests {
+			sortRunsByURL(got[i].Tests[j].Runs)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("failureSignatures(reversed) =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func sortRunsByURL(runs []Run) {
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].URL < runs[j].URL
+	})
+}
+
+func TestSignatureText(t *testing.T) {
+	tests := []struct {
+		messages []string
+		want     string
+	}{
+		{[]string{"value mismatch"}, "value mismatch"},
+		{[]string{"pod argocd-server-1 not ready", "pod argocd-repo-2 not ready"}, "pod * not ready"},
+		// consecutive differing words collapse into one "*"
+		{[]string{"failed to get a b now", "failed to get c d now"}, "failed to get * now"},
+		// punctuation and case do not make words differ
+		{[]string{"Timeout: waiting", "timeout waiting."}, "Timeout: waiting"},
+	}
+	for _, tt := range tests {
+		if got := signatureText(tt.messages); got != tt.want {
+			t.Errorf("signatureText(%q) = %q, want %q", tt.messages, got, tt.want)
+		}
+	}
+}
@@ -9,38 +9,67 @@ type Match struct {
 	FileType  string   `json:"filename"`
 	Context   []string `json:"context,omitempty"`
 	MoreLines int      `json:"moreLines,omitempty"`
+	// Bug and Issue describe the Bugzilla bug or Jira issue of bug and
+	// issue search results, whose keys are bug URLs instead of run URLs.
+	Bug   *TrackerInfo `json:"bugInfo,omitempty"`
+	Issue *TrackerInfo `json:"issueInfo,omitempty"`
 }
 
-// TestFailEntry ...
-type TestFailEntry struct {
-	PRList   []int
-	TestFail int
-	LastSeen *time.Time
-	LogURLs  map[int] /* pr number -> log urls */ []string
+// TrackerInfo is the summary search.ci returns for a bug or issue.
+type TrackerInfo struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
 }
 
-type TestFailEntryPriodic struct {
-	PRList   []string
+// TestFailEntry ...
+type TestFailEntry struct {
+	Groups   []string
+	Branches []string
 	TestFail int
 	LastSeen *time.Time
-	LogURLs  map[string] /* pr number -> log urls */ []string
+	// Name is the first line seen of the test, before normalization.
+	Name string
+	// Message and Location of the first failure, when an extractor found
+	// them.
+	Message  string
+	Location string
+	// Excerpt is from the most recent run, when enabled.
+	Excerpt *Excerpt
+	Runs    map[string] /* group (pr number, cluster version) -> runs */ []Run
 }
 
-type periodicJobData struct {
-	failure        string
-	clusterVersion string
-	url            string
-	flag           bool
+// Pattern is a labelled search of a target. Search is the search.ci regex
+// selecting the failure lines, and Regex extracts the test name from them:
+// its first group when it has one, otherwise the line without its matches.
+type Pattern struct {
+	Name   string `json:"name"`
+	Search string `json:"search"`
+	Regex  string `json:"regex,omitempty"`
+	// Extractor names a FailureExtractor finding the failed tests in the
+	// matched lines and their context, instead of Regex.
+	Extractor string `json:"extractor,omitempty"`
+	// Category groups the results in the report, Name if empty.
+	Category string `json:"category,omitempty"`
 }
 
 // Result ...
 type Result map[string]map[string][]Match
 
-// BlobStorage ...
+// BlobStorage is a content-addressed cache of downloaded artifacts, keyed by
+// the sha256 of their URL.
 type BlobStorage struct {
 	path string
 }
 
+// CacheMetadata is stored next to every cached blob as <hash>.json.
+type CacheMetadata struct {
+	SourceURL   string    `json:"sourceURL"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+	Size        int64     `json:"size"`
+	StatusCode  int       `json:"statusCode"`
+	ContentHash string    `json:"contentHash"`
+}
+
 type Config struct {
 	Pull      bool   `json:"pull"`
 	Periodic  bool   `json:"periodic"`
@@ -48,4 +77,63 @@ type Config struct {
 	RepoName  string `json:"repoName"`
 	RepoOrg   string `json:"repoOrg"`
 	SearchStr string `json:"searchStr"`
+	// SearchURL is the search.ci instance to query, DefaultSearchURL if empty.
+	SearchURL string `json:"searchURL,omitempty"`
+	// StorageURL is where job artifacts are downloaded from, DefaultStorageURL if empty.
+	StorageURL string `json:"storageURL,omitempty"`
+	// Extractor is the FailureExtractor of SearchStr, see Pattern.
+	Extractor string `json:"extractor,omitempty"`
+	// Rewrites are applied in order to failure lines to build the key tests
+	// are counted by, followed by the built-in Scrubbers, all of them if
+	// unset. The first line seen is shown.
+	Rewrites  []RewriteRule `json:"rewrites,omitempty"`
+	Scrubbers []string      `json:"scrubbers"`
+	// Patterns are searched instead of SearchStr and Regex when set.
+	Patterns []Pattern `json:"patterns,omitempty"`
+	// SearchType is the search.ci index failures are searched in, build logs
+	// or junit failures. SearchTypeBuildLog if empty.
+	SearchType SearchType `json:"searchType,omitempty"`
+	// Bugs enables searching Bugzilla and Jira for the names of the failing
+	// tests.
+	Bugs bool `json:"bugs,omitempty"`
+	// JUnit enables fetching the junit*.xml artifacts of every run.
+	JUnit bool `json:"junit,omitempty"`
+	// InfraRules classify runs as infra or test failures before the
+	// built-in rules. The failures of infra runs are counted unless
+	// InfraFailures is InfraExclude.
+	InfraRules    []ClassifierRule `json:"infraRules,omitempty"`
+	InfraFailures string           `json:"infraFailures,omitempty"`
+	// KuttlSteps enables parsing the kuttl step results of every build log.
+	KuttlSteps bool `json:"kuttlSteps,omitempty"`
+	// History enables enumerating every run of the failing jobs, to compute
+	// failure rates.
+	History bool `json:"history,omitempty"`
+	// Retests enables listing the runs of each failing job on each failing
+	// PR when History is off, to find the retests of the failures.
+	Retests bool `json:"retests,omitempty"`
+	// Concurrency is the number of build logs fetched in parallel.
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Search window and limits, see WithDefaults for the default values.
+	MaxAge     Duration `json:"maxAge,omitempty"`
+	MaxMatches int      `json:"maxMatches,omitempty"`
+	MaxBytes   int64    `json:"maxBytes,omitempty"`
+	Context    int      `json:"context,omitempty"`
+	// Excerpt is the number of build log lines shown before and after the
+	// most recent failure of each test, 0 to show none.
+	Excerpt int `json:"excerpt,omitempty"`
+
+	// Job selection, see JobFilter. Branches default to DefaultBranches and
+	// ExcludeJobs to DefaultExcludeJobs.
+	Branches    []string `json:"branches,omitempty"`
+	IncludeJobs []string `json:"includeJobs,omitempty"`
+	ExcludeJobs []string `json:"excludeJobs,omitempty"`
+
+	// Targets lists the repositories to analyze in one run. Targets inherit
+	// every option they leave unset from the top level config. Without
+	// targets, the top level config is the single target.
+	Targets []Config `json:"targets,omitempty"`
+
+	// set holds the JSON keys of the options the config file sets.
+	set map[string]bool
 }